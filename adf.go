@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// adfNode is one node of an Atlassian Document Format tree. Block nodes carry
+// Content; text nodes carry Text and Marks.
+type adfNode struct {
+	Type    string                 `json:"type"`
+	Text    string                 `json:"text,omitempty"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+	Marks   []adfMark              `json:"marks,omitempty"`
+	Content []adfNode              `json:"content,omitempty"`
+}
+
+type adfMark struct {
+	Type  string                 `json:"type"`
+	Attrs map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// NotionBlock is a Notion content block of a text-bearing type such as
+// paragraph, heading_1 or bulleted_list_item, or an image or file block
+// pointing at an external URL.
+type NotionBlock struct {
+	Type     string
+	RichText []NotionRichTextObject
+	Children []NotionBlock
+	// ExternalURL, when set, makes this an image or file block; RichText is
+	// then its caption.
+	ExternalURL string
+}
+
+// MarshalJSON nests the rich text under the block's type, which is how the
+// Notion API shapes block objects.
+func (b NotionBlock) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{"rich_text": b.RichText}
+	if b.ExternalURL != "" {
+		body = map[string]interface{}{
+			"type":     "external",
+			"external": map[string]interface{}{"url": b.ExternalURL},
+			"caption":  b.RichText,
+		}
+	}
+	if len(b.Children) > 0 {
+		body["children"] = b.Children
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"object": "block",
+		"type":   b.Type,
+		b.Type:   body,
+	})
+}
+
+type NotionRichTextObject struct {
+	Type        string             `json:"type"`
+	Text        NotionText         `json:"text"`
+	Annotations *NotionAnnotations `json:"annotations,omitempty"`
+}
+
+type NotionText struct {
+	Content string      `json:"content"`
+	Link    *NotionLink `json:"link,omitempty"`
+}
+
+type NotionLink struct {
+	URL string `json:"url"`
+}
+
+type NotionAnnotations struct {
+	Bold          bool `json:"bold,omitempty"`
+	Italic        bool `json:"italic,omitempty"`
+	Strikethrough bool `json:"strikethrough,omitempty"`
+	Code          bool `json:"code,omitempty"`
+}
+
+// adfToNotionBlocks converts an ADF document into Notion blocks. Paragraphs,
+// headings and bullet and numbered lists are mapped to their Notion
+// equivalents; any other node degrades to a paragraph of its plain text. A
+// plain string, as the v2 API returns for descriptions, becomes one paragraph
+// per line.
+func adfToNotionBlocks(adf json.RawMessage) []NotionBlock {
+	if len(adf) == 0 || string(adf) == "null" {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(adf, &text); err == nil {
+		return plainTextBlocks(text)
+	}
+
+	var doc adfNode
+	if err := json.Unmarshal(adf, &doc); err != nil {
+		return plainTextBlocks(string(adf))
+	}
+
+	if doc.Type == "doc" {
+		return adfBlocks(doc.Content)
+	}
+	return adfBlocks([]adfNode{doc})
+}
+
+func adfBlocks(nodes []adfNode) []NotionBlock {
+	var blocks []NotionBlock
+	for _, node := range nodes {
+		blocks = append(blocks, adfBlock(node)...)
+	}
+	return blocks
+}
+
+func adfBlock(node adfNode) []NotionBlock {
+	switch node.Type {
+	case "paragraph":
+		return []NotionBlock{{Type: "paragraph", RichText: adfRichText(node.Content)}}
+	case "heading":
+		return []NotionBlock{{Type: headingType(node.Attrs), RichText: adfRichText(node.Content)}}
+	case "bulletList":
+		return adfListItems(node.Content, "bulleted_list_item")
+	case "orderedList":
+		return adfListItems(node.Content, "numbered_list_item")
+	}
+
+	text := adfPlainText(node)
+	if text == "" {
+		return nil
+	}
+	return []NotionBlock{{Type: "paragraph", RichText: []NotionRichTextObject{plainRichText(text)}}}
+}
+
+// adfListItems turns listItem nodes into list blocks. The item's first
+// paragraph becomes its text and anything after it, such as a nested list,
+// becomes its children.
+func adfListItems(items []adfNode, blockType string) []NotionBlock {
+	var blocks []NotionBlock
+	for _, item := range items {
+		block := NotionBlock{Type: blockType, RichText: []NotionRichTextObject{}}
+
+		content := item.Content
+		if len(content) > 0 && content[0].Type == "paragraph" {
+			block.RichText = adfRichText(content[0].Content)
+			content = content[1:]
+		}
+		block.Children = adfBlocks(content)
+
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+func headingType(attrs map[string]interface{}) string {
+	level, _ := attrs["level"].(float64)
+	switch {
+	case level <= 1:
+		return "heading_1"
+	case level == 2:
+		return "heading_2"
+	}
+	// Notion only has three heading levels.
+	return "heading_3"
+}
+
+func adfRichText(nodes []adfNode) []NotionRichTextObject {
+	richText := []NotionRichTextObject{}
+	for _, node := range nodes {
+		switch node.Type {
+		case "text":
+			rt := plainRichText(node.Text)
+			rt.Annotations = adfAnnotations(node.Marks)
+			rt.Text.Link = adfLink(node.Marks)
+			richText = append(richText, rt)
+		case "hardBreak":
+			richText = append(richText, plainRichText("\n"))
+		default:
+			if text := adfPlainText(node); text != "" {
+				richText = append(richText, plainRichText(text))
+			}
+		}
+	}
+	return richText
+}
+
+func adfAnnotations(marks []adfMark) *NotionAnnotations {
+	var annotations NotionAnnotations
+	for _, mark := range marks {
+		switch mark.Type {
+		case "strong":
+			annotations.Bold = true
+		case "em":
+			annotations.Italic = true
+		case "strike":
+			annotations.Strikethrough = true
+		case "code":
+			annotations.Code = true
+		}
+	}
+
+	if annotations == (NotionAnnotations{}) {
+		return nil
+	}
+	return &annotations
+}
+
+// adfLink returns the target of a link mark among marks. Notion rejects the
+// whole write over a link it can't parse, so only absolute http, https and
+// mailto links are kept; the text stays either way.
+func adfLink(marks []adfMark) *NotionLink {
+	for _, mark := range marks {
+		if mark.Type != "link" {
+			continue
+		}
+		href, _ := mark.Attrs["href"].(string)
+		target, err := url.Parse(href)
+		if err != nil {
+			return nil
+		}
+		switch {
+		case (target.Scheme == "http" || target.Scheme == "https") && target.Host != "":
+		case target.Scheme == "mailto" && target.Opaque != "":
+		default:
+			return nil
+		}
+		return &NotionLink{URL: href}
+	}
+	return nil
+}
+
+// adfPlainText flattens a node to its text, falling back to the text or
+// shortName attributes used by mentions, emoji and similar inline nodes.
+func adfPlainText(node adfNode) string {
+	if node.Text != "" {
+		return node.Text
+	}
+	if node.Type == "hardBreak" {
+		return "\n"
+	}
+	if len(node.Content) == 0 {
+		for _, attr := range []string{"text", "shortName"} {
+			if value, ok := node.Attrs[attr].(string); ok {
+				return value
+			}
+		}
+		return ""
+	}
+
+	var b strings.Builder
+	for _, child := range node.Content {
+		b.WriteString(adfPlainText(child))
+	}
+	return b.String()
+}
+
+func plainRichText(content string) NotionRichTextObject {
+	return NotionRichTextObject{Type: "text", Text: NotionText{Content: content}}
+}
+
+func plainTextBlocks(text string) []NotionBlock {
+	var blocks []NotionBlock
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		blocks = append(blocks, NotionBlock{Type: "paragraph", RichText: []NotionRichTextObject{plainRichText(line)}})
+	}
+	return blocks
+}
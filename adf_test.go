@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestADFToNotionBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		adf      string
+		expected string
+	}{
+		{
+			"paragraph",
+			`{"type":"doc","version":1,"content":[{"type":"paragraph","content":[{"type":"text","text":"Hello"}]}]}`,
+			`[{"object":"block","type":"paragraph","paragraph":{"rich_text":[{"type":"text","text":{"content":"Hello"}}]}}]`,
+		},
+		{
+			"bold and italic marks",
+			`{"type":"doc","version":1,"content":[{"type":"paragraph","content":[{"type":"text","text":"Bold","marks":[{"type":"strong"}]},{"type":"text","text":" and "},{"type":"text","text":"both","marks":[{"type":"strong"},{"type":"em"}]}]}]}`,
+			`[{"object":"block","type":"paragraph","paragraph":{"rich_text":[{"type":"text","text":{"content":"Bold"},"annotations":{"bold":true}},{"type":"text","text":{"content":" and "}},{"type":"text","text":{"content":"both"},"annotations":{"bold":true,"italic":true}}]}}]`,
+		},
+		{
+			"link, code and strike marks",
+			`{"type":"doc","version":1,"content":[{"type":"paragraph","content":[{"type":"text","text":"See ","marks":[{"type":"strike"}]},{"type":"text","text":"the spec","marks":[{"type":"strong"},{"type":"link","attrs":{"href":"https://example.com/spec"}}]},{"type":"text","text":" for "},{"type":"text","text":"retry()","marks":[{"type":"code"}]}]}]}`,
+			`[{"object":"block","type":"paragraph","paragraph":{"rich_text":[{"type":"text","text":{"content":"See "},"annotations":{"strikethrough":true}},{"type":"text","text":{"content":"the spec","link":{"url":"https://example.com/spec"}},"annotations":{"bold":true}},{"type":"text","text":{"content":" for "}},{"type":"text","text":{"content":"retry()"},"annotations":{"code":true}}]}}]`,
+		},
+		{
+			"relative link",
+			`{"type":"doc","version":1,"content":[{"type":"paragraph","content":[{"type":"text","text":"Elsewhere","marks":[{"type":"link","attrs":{"href":"/browse/TU-2"}}]}]}]}`,
+			`[{"object":"block","type":"paragraph","paragraph":{"rich_text":[{"type":"text","text":{"content":"Elsewhere"}}]}}]`,
+		},
+		{
+			"heading",
+			`{"type":"doc","version":1,"content":[{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Goals"}]}]}`,
+			`[{"object":"block","type":"heading_2","heading_2":{"rich_text":[{"type":"text","text":{"content":"Goals"}}]}}]`,
+		},
+		{
+			"deep heading",
+			`{"type":"doc","version":1,"content":[{"type":"heading","attrs":{"level":5},"content":[{"type":"text","text":"Notes"}]}]}`,
+			`[{"object":"block","type":"heading_3","heading_3":{"rich_text":[{"type":"text","text":{"content":"Notes"}}]}}]`,
+		},
+		{
+			"bullet list",
+			`{"type":"doc","version":1,"content":[{"type":"bulletList","content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"One"}]}]},{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"Two"}]}]}]}]}`,
+			`[{"object":"block","type":"bulleted_list_item","bulleted_list_item":{"rich_text":[{"type":"text","text":{"content":"One"}}]}},{"object":"block","type":"bulleted_list_item","bulleted_list_item":{"rich_text":[{"type":"text","text":{"content":"Two"}}]}}]`,
+		},
+		{
+			"nested list",
+			`{"type":"doc","version":1,"content":[{"type":"bulletList","content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"Parent"}]},{"type":"bulletList","content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"Child"}]}]}]}]}]}]}`,
+			`[{"object":"block","type":"bulleted_list_item","bulleted_list_item":{"rich_text":[{"type":"text","text":{"content":"Parent"}}],"children":[{"object":"block","type":"bulleted_list_item","bulleted_list_item":{"rich_text":[{"type":"text","text":{"content":"Child"}}]}}]}}]`,
+		},
+		{
+			"unsupported node",
+			`{"type":"doc","version":1,"content":[{"type":"panel","attrs":{"panelType":"info"},"content":[{"type":"paragraph","content":[{"type":"text","text":"Heads up"}]}]}]}`,
+			`[{"object":"block","type":"paragraph","paragraph":{"rich_text":[{"type":"text","text":{"content":"Heads up"}}]}}]`,
+		},
+		{
+			"plain string",
+			`"First line\nSecond line"`,
+			`[{"object":"block","type":"paragraph","paragraph":{"rich_text":[{"type":"text","text":{"content":"First line"}}]}},{"object":"block","type":"paragraph","paragraph":{"rich_text":[{"type":"text","text":{"content":"Second line"}}]}}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := adfToNotionBlocks(json.RawMessage(tt.adf))
+			assertJSON(t, blocks, tt.expected)
+		})
+	}
+}
+
+func TestADFToNotionBlocksEmpty(t *testing.T) {
+	if blocks := adfToNotionBlocks(json.RawMessage("null")); blocks != nil {
+		t.Errorf("Expected no blocks for a null description, got %v", blocks)
+	}
+}
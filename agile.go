@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// agilePage is the paging envelope of the /rest/agile/1.0 endpoints. Lists
+// such as a board's sprints end with isLast; issue lists report a total
+// instead.
+type agilePage struct {
+	StartAt int  `json:"startAt"`
+	Total   int  `json:"total"`
+	IsLast  bool `json:"isLast"`
+}
+
+// FetchBoardSprints returns every sprint of the Jira Software board boardID,
+// closed ones included. Unlike FetchIssues it uses the Agile API, which
+// lists sprints without going through JQL.
+func (c *JiraClient) FetchBoardSprints(ctx context.Context, boardID int) ([]Sprint, error) {
+	path := "/rest/agile/1.0/board/" + strconv.Itoa(boardID) + "/sprint"
+	return collectAll(func(cursor string) ([]Sprint, string, error) {
+		var page struct {
+			agilePage
+			Values []Sprint `json:"values"`
+		}
+		if err := c.getAgilePage(ctx, path, cursor, url.Values{}, &page); err != nil {
+			return nil, "", err
+		}
+		return page.Values, page.next(len(page.Values)), nil
+	})
+}
+
+// FetchSprintIssues returns the issues in sprint sprintID, with fields, or
+// the fields FetchIssues reads by default.
+func (c *JiraClient) FetchSprintIssues(ctx context.Context, sprintID int, fields ...string) ([]Issue, error) {
+	if len(fields) == 0 {
+		fields = defaultFields
+	}
+	query := url.Values{"fields": {strings.Join(fields, ",")}}
+
+	path := "/rest/agile/1.0/sprint/" + strconv.Itoa(sprintID) + "/issue"
+	issues, err := collectAll(func(cursor string) ([]Issue, string, error) {
+		var page struct {
+			agilePage
+			Issues []Issue `json:"issues"`
+		}
+		if err := c.getAgilePage(ctx, path, cursor, query, &page); err != nil {
+			return nil, "", err
+		}
+		return page.Issues, page.next(len(page.Issues)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.log().Info("fetched sprint issues", "sprintID", sprintID, "count", len(issues))
+	return issues, nil
+}
+
+// next returns the startAt of the page after this one, which held count
+// items, or "" after the last page. An empty page ends the list too, so a
+// total that counts hidden items can't loop forever.
+func (p agilePage) next(count int) string {
+	startAt := p.StartAt + count
+	if p.IsLast || count == 0 || (p.Total > 0 && startAt >= p.Total) {
+		return ""
+	}
+	return strconv.Itoa(startAt)
+}
+
+// getAgilePage decodes the page of path starting at startAt, or at the first
+// item when startAt is "".
+func (c *JiraClient) getAgilePage(ctx context.Context, path, startAt string, query url.Values, out interface{}) error {
+	if startAt != "" {
+		query.Set("startAt", startAt)
+	}
+	pageURL := joinURL(c.baseURL, path)
+	if len(query) > 0 {
+		pageURL += "?" + query.Encode()
+	}
+
+	resp, err := c.do(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return httpError(resp.StatusCode, readErrorBody(resp))
+	}
+	return c.decode(resp, out)
+}
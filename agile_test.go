@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFetchBoardSprints(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/rest/agile/1.0/board/7/sprint" {
+			t.Errorf("Expected the sprints of board 7 to be requested, got %s", r.URL.Path)
+		}
+
+		switch r.URL.Query().Get("startAt") {
+		case "":
+			_, _ = w.Write([]byte(`{"maxResults": 1, "startAt": 0, "isLast": false, "values": [
+				{"id": 1, "name": "Sprint 1", "state": "closed", "startDate": "2024-05-01T09:00:00.000Z", "endDate": "2024-05-15T09:00:00.000Z"}
+			]}`))
+		case "1":
+			_, _ = w.Write([]byte(`{"maxResults": 1, "startAt": 1, "isLast": true, "values": [
+				{"id": 2, "name": "Sprint 2", "state": "future"}
+			]}`))
+		default:
+			t.Errorf("Unexpected startAt %q", r.URL.Query().Get("startAt"))
+		}
+	}))
+	defer ts.Close()
+
+	sprints, err := newTestJiraClient(t, ts.URL).FetchBoardSprints(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("Error fetching sprints: %v", err)
+	}
+
+	if requests != 2 || len(sprints) != 2 {
+		t.Fatalf("Expected 2 sprints over 2 requests, got %+v after %d requests", sprints, requests)
+	}
+	first := sprints[0]
+	if first.Name != "Sprint 1" || first.State != "closed" || !first.StartDate.Equal(time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)) || !first.EndDate.Equal(time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Sprint 1 from May 1 to May 15, got %+v", first)
+	}
+	if sprints[1].Name != "Sprint 2" || !sprints[1].StartDate.IsZero() {
+		t.Errorf("Expected the unplanned Sprint 2 to have no dates, got %+v", sprints[1])
+	}
+}
+
+func TestFetchSprintIssues(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/agile/1.0/sprint/2/issue" {
+			t.Errorf("Expected the issues of sprint 2 to be requested, got %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("fields"); got != "summary,status" {
+			t.Errorf("Expected fields summary,status, got %q", got)
+		}
+
+		if r.URL.Query().Get("startAt") == "2" {
+			_, _ = w.Write([]byte(`{"startAt": 2, "maxResults": 2, "total": 3, "issues": [{"key": "TU-3"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"startAt": 0, "maxResults": 2, "total": 3, "issues": [{"key": "TU-1"}, {"key": "TU-2"}]}`))
+	}))
+	defer ts.Close()
+
+	issues, err := newTestJiraClient(t, ts.URL).FetchSprintIssues(context.Background(), 2, "summary", "status")
+	if err != nil {
+		t.Fatalf("Error fetching sprint issues: %v", err)
+	}
+
+	if len(issues) != 3 || issues[0].Key != "TU-1" || issues[2].Key != "TU-3" {
+		t.Errorf("Expected TU-1 to TU-3, got %+v", issues)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Attachment is the metadata of a file attached to a Jira issue. Content is
+// the URL the file is downloaded from.
+type Attachment struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	Content  string `json:"content"`
+}
+
+// FetchAttachments lists the files attached to an issue.
+func (c *JiraClient) FetchAttachments(ctx context.Context, issueKey string) ([]Attachment, error) {
+	issueURL, err := c.issueURL(issueKey)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(ctx, "GET", issueURL+"?fields=attachment", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpError(resp.StatusCode, readErrorBody(resp))
+	}
+
+	var issue struct {
+		Fields struct {
+			Attachment []Attachment `json:"attachment"`
+		} `json:"fields"`
+	}
+	if err := c.decode(resp, &issue); err != nil {
+		return nil, err
+	}
+
+	return issue.Fields.Attachment, nil
+}
+
+// DownloadAttachment returns the contents of an attachment's Content URL.
+// Files larger than maxSize bytes are rejected; a maxSize of zero or less
+// means no limit. The URL must be on the client's Jira host, so credentials
+// are never sent elsewhere.
+func (c *JiraClient) DownloadAttachment(ctx context.Context, contentURL string, maxSize int64) ([]byte, error) {
+	if err := c.checkSameHost(contentURL); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doWith(ctx, "GET", contentURL, nil, contentHeaders{Accept: "application/octet-stream"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpError(resp.StatusCode, readErrorBody(resp))
+	}
+
+	reader := io.Reader(resp.Body)
+	if maxSize > 0 {
+		reader = io.LimitReader(resp.Body, maxSize+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("attachment %s is larger than %d bytes", contentURL, maxSize)
+	}
+
+	return data, nil
+}
+
+func (c *JiraClient) checkSameHost(rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return err
+	}
+
+	if target.Scheme != base.Scheme || target.Host != base.Host {
+		return fmt.Errorf("refusing to download %s: not on the Jira host %s", rawURL, base.Host)
+	}
+	return nil
+}
+
+// attachmentBlocks embeds attachments as Notion image blocks, or file blocks
+// for anything that isn't an image, linking to each file's Jira URL.
+// Attachments larger than maxSize bytes are skipped; a maxSize of zero or
+// less means no limit.
+func attachmentBlocks(attachments []Attachment, maxSize int64) []NotionBlock {
+	var blocks []NotionBlock
+	for _, attachment := range attachments {
+		if maxSize > 0 && attachment.Size > maxSize {
+			continue
+		}
+
+		blockType := "file"
+		if strings.HasPrefix(attachment.MimeType, "image/") {
+			blockType = "image"
+		}
+		blocks = append(blocks, NotionBlock{
+			Type:        blockType,
+			RichText:    []NotionRichTextObject{plainRichText(attachment.Filename)},
+			ExternalURL: attachment.Content,
+		})
+	}
+	return blocks
+}
+
+// AppendAttachments embeds attachments at the end of a Notion page's
+// content, skipping any larger than maxSize bytes.
+func (c *NotionClient) AppendAttachments(ctx context.Context, pageID string, attachments []Attachment, maxSize int64) error {
+	blocks := attachmentBlocks(attachments, maxSize)
+	if len(blocks) < len(attachments) {
+		c.log().Info("skipped attachments over the size limit", "pageID", pageID, "skipped", len(attachments)-len(blocks), "maxSize", maxSize)
+	}
+
+	return c.AppendBlocks(ctx, pageID, blocks)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func attachmentsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/2/issue/TU-1":
+			if got := r.URL.Query().Get("fields"); got != "attachment" {
+				t.Errorf("Expected fields to be %s, got %s", "attachment", got)
+			}
+			response := map[string]interface{}{"fields": map[string]interface{}{"attachment": []Attachment{
+				{ID: "1", Filename: "screenshot.png", MimeType: "image/png", Size: 5, Content: ts.URL + "/secure/attachment/1/screenshot.png"},
+				{ID: "2", Filename: "spec.pdf", MimeType: "application/pdf", Size: 5000, Content: ts.URL + "/secure/attachment/2/spec.pdf"},
+			}}}
+			if err := json.NewEncoder(w).Encode(response); err != nil {
+				t.Fatalf("Error encoding response: %v", err)
+			}
+		case "/secure/attachment/1/screenshot.png":
+			if got := r.Header.Get("Accept"); got != "application/octet-stream" {
+				t.Errorf("Expected Accept header to be %s, got %s", "application/octet-stream", got)
+			}
+			w.Write([]byte("bytes"))
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	}))
+	return ts
+}
+
+func TestFetchAndDownloadAttachments(t *testing.T) {
+	ts := attachmentsServer(t)
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+	attachments, err := client.FetchAttachments(context.Background(), "TU-1")
+	if err != nil {
+		t.Fatalf("Error fetching attachments: %v", err)
+	}
+
+	if len(attachments) != 2 || attachments[0].Filename != "screenshot.png" || attachments[0].MimeType != "image/png" {
+		t.Fatalf("Expected the two attachments, got %+v", attachments)
+	}
+
+	data, err := client.DownloadAttachment(context.Background(), attachments[0].Content, 1024)
+	if err != nil {
+		t.Fatalf("Error downloading attachment: %v", err)
+	}
+	if string(data) != "bytes" {
+		t.Errorf("Expected %q, got %q", "bytes", data)
+	}
+
+	if _, err := client.DownloadAttachment(context.Background(), attachments[0].Content, 2); err == nil {
+		t.Error("Expected an error for an attachment over the size limit")
+	}
+
+	if _, err := client.DownloadAttachment(context.Background(), "https://elsewhere.example.com/file", 0); err == nil {
+		t.Error("Expected an error for a URL off the Jira host")
+	}
+}
+
+func TestAttachmentBlocks(t *testing.T) {
+	blocks := attachmentBlocks([]Attachment{
+		{Filename: "screenshot.png", MimeType: "image/png", Size: 5, Content: "https://jira.example.com/a/1"},
+		{Filename: "huge.mov", MimeType: "video/quicktime", Size: 1 << 30, Content: "https://jira.example.com/a/2"},
+	}, 10<<20)
+
+	assertJSON(t, blocks, `[{"object": "block", "type": "image", "image": {
+		"type": "external",
+		"external": {"url": "https://jira.example.com/a/1"},
+		"caption": [{"type": "text", "text": {"content": "screenshot.png"}}]
+	}}]`)
+}
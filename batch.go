@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// FieldUpdate is one custom field change for UpdateCustomFieldsBatch.
+type FieldUpdate struct {
+	IssueKey string
+	FieldID  string
+	Value    interface{}
+}
+
+// FieldUpdateResult records the outcome of one FieldUpdate.
+type FieldUpdateResult struct {
+	IssueKey   string
+	StatusCode int
+	Err        error
+}
+
+// UpdateCustomFieldsBatch applies updates using at most concurrency parallel
+// requests. Results are returned in the same order as updates, and the error
+// joins every failed update so one bad issue doesn't hide the others.
+// Cancelling ctx stops handing out updates: those already sent are given
+// defaultShutdownTimeout to finish, and the rest fail with ctx's error.
+func (c *JiraClient) UpdateCustomFieldsBatch(ctx context.Context, updates []FieldUpdate, concurrency int) ([]FieldUpdateResult, error) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]FieldUpdateResult, len(updates))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				u := updates[i]
+				work, done := drainContext(ctx, defaultShutdownTimeout)
+				statusCode, _, err := c.UpdateCustomField(work, u.IssueKey, u.FieldID, u.Value)
+				done()
+				results[i] = FieldUpdateResult{IssueKey: u.IssueKey, StatusCode: statusCode, Err: err}
+			}
+		}()
+	}
+
+feed:
+	for i := range updates {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			for j := i; j < len(updates); j++ {
+				results[j] = FieldUpdateResult{IssueKey: updates[j].IssueKey, Err: ctx.Err()}
+			}
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.IssueKey, r.Err))
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateCustomFieldsBatch(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		if strings.HasSuffix(r.URL.Path, "/TU-7") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	defer ts.Close()
+
+	var updates []FieldUpdate
+	for i := 1; i <= 10; i++ {
+		updates = append(updates, FieldUpdate{IssueKey: "TU-" + strconv.Itoa(i), FieldID: "customfield_10506", Value: map[string]interface{}{"id": "11755"}})
+	}
+
+	client := newTestJiraClient(t, ts.URL)
+	results, err := client.UpdateCustomFieldsBatch(context.Background(), updates, 4)
+	if err == nil || !strings.Contains(err.Error(), "TU-7") {
+		t.Errorf("Expected an aggregate error mentioning TU-7, got %v", err)
+	}
+
+	if len(results) != len(updates) {
+		t.Fatalf("Expected %d results, got %d", len(updates), len(results))
+	}
+
+	for i, result := range results {
+		if result.IssueKey != updates[i].IssueKey {
+			t.Errorf("Expected result %d key to be %s, got %s", i, updates[i].IssueKey, result.IssueKey)
+		}
+
+		expectedStatus := http.StatusNoContent
+		if result.IssueKey == "TU-7" {
+			expectedStatus = http.StatusBadRequest
+		}
+		if result.StatusCode != expectedStatus {
+			t.Errorf("Expected %s status code %d, got %d", result.IssueKey, expectedStatus, result.StatusCode)
+		}
+	}
+
+	if maxInFlight > 4 {
+		t.Errorf("Expected at most %d concurrent requests, got %d", 4, maxInFlight)
+	}
+}
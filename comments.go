@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Comment is one comment on a Jira issue. Body is ADF on the v3 API and a
+// plain string on v2; adfToNotionBlocks handles both.
+type Comment struct {
+	ID      string          `json:"id"`
+	Author  *User           `json:"author"`
+	Created string          `json:"created"`
+	Body    json.RawMessage `json:"body"`
+}
+
+type commentsResponse struct {
+	StartAt    int       `json:"startAt"`
+	MaxResults int       `json:"maxResults"`
+	Total      int       `json:"total"`
+	Comments   []Comment `json:"comments"`
+}
+
+// fetchComments is a wrapper around JiraClient.FetchComments for callers that
+// don't keep a client around.
+func fetchComments(ctx context.Context, issueKey, encodedCredentials, baseURL string) ([]Comment, error) {
+	client, err := NewJiraClient(baseURL, encodedCredentials, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.FetchComments(ctx, issueKey)
+}
+
+// FetchComments returns every comment on an issue, oldest first, following
+// startAt/maxResults/total like FetchIssues.
+func (c *JiraClient) FetchComments(ctx context.Context, issueKey string) ([]Comment, error) {
+	issueURL, err := c.issueURL(issueKey)
+	if err != nil {
+		return nil, err
+	}
+
+	collected := 0
+	comments, err := collectAll(func(string) ([]Comment, string, error) {
+		query := url.Values{}
+		query.Set("startAt", strconv.Itoa(collected))
+		query.Set("maxResults", strconv.Itoa(defaultPageSize))
+
+		page, err := c.fetchCommentPage(ctx, issueURL+"/comment?"+query.Encode())
+		if err != nil {
+			c.log().Error("comment fetch failed", "issueKey", issueKey, "startAt", collected, "error", err)
+			return nil, "", err
+		}
+
+		collected += len(page.Comments)
+		if len(page.Comments) == 0 || collected >= page.Total {
+			return page.Comments, "", nil
+		}
+		return page.Comments, strconv.Itoa(collected), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func (c *JiraClient) fetchCommentPage(ctx context.Context, commentURL string) (*commentsResponse, error) {
+	resp, err := c.do(ctx, "GET", commentURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpError(resp.StatusCode, readErrorBody(resp))
+	}
+
+	var page commentsResponse
+	if err := c.decode(resp, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// commentBlocks renders comments as Notion blocks: a bold paragraph naming
+// the author and time, followed by the comment body.
+func commentBlocks(comments []Comment) []NotionBlock {
+	var blocks []NotionBlock
+	for _, comment := range comments {
+		author := "Unknown"
+		if comment.Author != nil && comment.Author.DisplayName != "" {
+			author = comment.Author.DisplayName
+		}
+
+		heading := plainRichText(author + " — " + comment.Created)
+		heading.Annotations = &NotionAnnotations{Bold: true}
+		blocks = append(blocks, NotionBlock{Type: "paragraph", RichText: []NotionRichTextObject{heading}})
+		blocks = append(blocks, adfToNotionBlocks(comment.Body)...)
+	}
+	return blocks
+}
+
+// AppendComments adds comments to the end of a Notion page's content.
+func (c *NotionClient) AppendComments(ctx context.Context, pageID string, comments []Comment) error {
+	return c.AppendBlocks(ctx, pageID, commentBlocks(comments))
+}
+
+// postComment is a wrapper around JiraClient.PostComment for callers that
+// don't keep a client around.
+func postComment(ctx context.Context, issueKey, body, encodedCredentials, baseURL string) (Comment, error) {
+	client, err := NewJiraClient(baseURL, encodedCredentials, nil)
+	if err != nil {
+		return Comment{}, err
+	}
+	return client.PostComment(ctx, issueKey, body)
+}
+
+// PostComment adds a comment with the plain text body to an issue and
+// returns it as Jira stored it. With APIVersion3 the body is sent as ADF,
+// built by textADF; v2 takes the text as is.
+func (c *JiraClient) PostComment(ctx context.Context, issueKey, body string) (Comment, error) {
+	commentURL, err := c.issueURL(issueKey)
+	if err != nil {
+		return Comment{}, err
+	}
+	commentURL += "/comment"
+
+	var payload map[string]interface{}
+	if c.APIVersion == APIVersion3 {
+		commentURL = joinURL(c.baseURL, "/rest/api/3/issue/"+url.PathEscape(issueKey)+"/comment")
+		payload = map[string]interface{}{"body": textADF(body)}
+	} else {
+		payload = map[string]interface{}{"body": body}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Comment{}, err
+	}
+	if c.DryRun {
+		logDryRun(c.log(), "POST", commentURL, data)
+		return Comment{}, nil
+	}
+
+	resp, err := c.do(ctx, "POST", commentURL, data)
+	if err != nil {
+		return Comment{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := httpError(resp.StatusCode, readErrorBody(resp))
+		c.log().Error("comment post failed", "issueKey", issueKey, "statusCode", resp.StatusCode, "error", err)
+		return Comment{}, err
+	}
+
+	var comment Comment
+	if err := c.decode(resp, &comment); err != nil {
+		return Comment{}, err
+	}
+	c.log().Info("comment posted", "issueKey", issueKey, "commentID", comment.ID)
+	return comment, nil
+}
+
+// textADF wraps plain text in a minimal ADF document: a paragraph per block
+// of text separated by a blank line, with hard breaks for the line breaks
+// within it.
+func textADF(text string) map[string]interface{} {
+	content := []interface{}{}
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+
+		var nodes []interface{}
+		for i, line := range strings.Split(paragraph, "\n") {
+			if i > 0 {
+				nodes = append(nodes, map[string]interface{}{"type": "hardBreak"})
+			}
+			if line != "" {
+				nodes = append(nodes, map[string]interface{}{"type": "text", "text": line})
+			}
+		}
+		content = append(content, map[string]interface{}{"type": "paragraph", "content": nodes})
+	}
+	return map[string]interface{}{"type": "doc", "version": 1, "content": content}
+}
+
+// NotionComment is a comment on a Notion page.
+type NotionComment struct {
+	ID       string            `json:"id"`
+	RichText []notionPlainText `json:"rich_text"`
+}
+
+// Text returns the comment as plain text.
+func (c NotionComment) Text() string {
+	var b strings.Builder
+	for _, part := range c.RichText {
+		b.WriteString(part.PlainText)
+	}
+	return b.String()
+}
+
+// FetchPageComments returns the comments on a Notion page, oldest first,
+// following next_cursor like QueryDatabase.
+func (c *NotionClient) FetchPageComments(ctx context.Context, pageID string) ([]NotionComment, error) {
+	return collectAll(func(cursor string) ([]NotionComment, string, error) {
+		query := url.Values{}
+		query.Set("block_id", pageID)
+		query.Set("page_size", strconv.Itoa(notionMaxPageSize))
+		if cursor != "" {
+			query.Set("start_cursor", cursor)
+		}
+
+		var resp struct {
+			Results    []NotionComment `json:"results"`
+			HasMore    bool            `json:"has_more"`
+			NextCursor string          `json:"next_cursor"`
+		}
+		if _, err := c.do(ctx, "GET", c.baseURL+"/comments?"+query.Encode(), nil, &resp); err != nil {
+			return nil, "", err
+		}
+		if !resp.HasMore {
+			return resp.Results, "", nil
+		}
+		return resp.Results, resp.NextCursor, nil
+	})
+}
+
+// PushNotionComments posts the comments on a Notion page to its Jira issue,
+// oldest first, and returns how many it posted. state records each posted
+// comment, so none is posted twice, even across runs. Unlike a sync's, it is
+// required: without it every run would post every comment again.
+func PushNotionComments(ctx context.Context, jira *JiraClient, notion *NotionClient, pageID, issueKey string, state *SyncState) (int, error) {
+	if state == nil {
+		return 0, errors.New("pushing Notion comments needs a SyncState to record posted comments")
+	}
+
+	comments, err := notion.FetchPageComments(ctx, pageID)
+	if err != nil {
+		return 0, err
+	}
+
+	posted := 0
+	for _, comment := range comments {
+		if state.CommentPosted(comment.ID) || strings.TrimSpace(comment.Text()) == "" {
+			continue
+		}
+
+		jiraComment, err := jira.PostComment(ctx, issueKey, comment.Text())
+		if err != nil {
+			return posted, err
+		}
+		if jira.DryRun {
+			continue
+		}
+		if err := state.MarkCommentPosted(comment.ID, jiraComment.ID); err != nil {
+			return posted, err
+		}
+		posted++
+	}
+	return posted, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchComments(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/issue/TU-1/comment" {
+			t.Errorf("Expected path to be %s, got %s", "/rest/api/2/issue/TU-1/comment", r.URL.Path)
+		}
+
+		// Serve one comment per page to exercise pagination.
+		response := commentsResponse{Total: 2}
+		switch r.URL.Query().Get("startAt") {
+		case "0":
+			response.Comments = []Comment{{ID: "1", Author: &User{DisplayName: "Ada"}, Created: "2024-05-01T10:00:00.000+0000", Body: json.RawMessage(`"First"`)}}
+		case "1":
+			response.Comments = []Comment{{ID: "2", Author: &User{DisplayName: "Grace"}, Created: "2024-05-02T10:00:00.000+0000", Body: json.RawMessage(`"Second"`)}}
+		}
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	comments, err := fetchComments(context.Background(), "TU-1", "encodedCredentials", ts.URL)
+	if err != nil {
+		t.Fatalf("Error fetching comments: %v", err)
+	}
+
+	if len(comments) != 2 {
+		t.Fatalf("Expected 2 comments, got %d", len(comments))
+	}
+
+	if comments[0].Author.DisplayName != "Ada" || string(comments[0].Body) != `"First"` {
+		t.Errorf("Expected the first comment from Ada, got %+v", comments[0])
+	}
+
+	if comments[1].ID != "2" || comments[1].Created != "2024-05-02T10:00:00.000+0000" {
+		t.Errorf("Expected the second comment, got %+v", comments[1])
+	}
+}
+
+func TestAppendComments(t *testing.T) {
+	var children []json.RawMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PATCH" || r.URL.Path != "/blocks/page-1/children" {
+			t.Errorf("Expected PATCH /blocks/page-1/children, got %s %s", r.Method, r.URL.Path)
+		}
+
+		var body struct {
+			Children []json.RawMessage `json:"children"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding request: %v", err)
+		}
+		children = body.Children
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	comments := []Comment{{Author: &User{DisplayName: "Ada"}, Created: "2024-05-01", Body: json.RawMessage(`"Looks good"`)}}
+	if err := newTestNotionClient(ts.URL).AppendComments(context.Background(), "page-1", comments); err != nil {
+		t.Fatalf("Error appending comments: %v", err)
+	}
+
+	if len(children) != 2 {
+		t.Fatalf("Expected a heading and a body block, got %d blocks", len(children))
+	}
+	assertJSON(t, children[1], `{"object":"block","type":"paragraph","paragraph":{"rich_text":[{"type":"text","text":{"content":"Looks good"}}]}}`)
+}
+
+func TestPostComment(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/rest/api/3/issue/TU-1/comment" {
+			t.Errorf("Expected POST /rest/api/3/issue/TU-1/comment, got %s %s", r.Method, r.URL.Path)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		expected := `{"body":{"content":[` +
+			`{"content":[{"text":"Looks good","type":"text"},{"type":"hardBreak"},{"text":"Ship it","type":"text"}],"type":"paragraph"},` +
+			`{"content":[{"text":"Thanks","type":"text"}],"type":"paragraph"}` +
+			`],"type":"doc","version":1}}`
+		if string(body) != expected {
+			t.Errorf("Expected body %s, got %s", expected, body)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "10010"}`))
+	}))
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+	client.APIVersion = APIVersion3
+	comment, err := client.PostComment(context.Background(), "TU-1", "Looks good\nShip it\n\nThanks")
+	if err != nil {
+		t.Fatalf("Error posting comment: %v", err)
+	}
+
+	if comment.ID != "10010" {
+		t.Errorf("Expected comment id 10010, got %q", comment.ID)
+	}
+}
+
+func TestPushNotionCommentsPostsOnce(t *testing.T) {
+	posts := 0
+	jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "10010"}`))
+	}))
+	defer jiraServer.Close()
+
+	notionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/comments" || r.URL.Query().Get("block_id") != "page-1" {
+			t.Errorf("Expected the comments of page-1 to be requested, got %s", r.URL)
+		}
+		w.Write([]byte(`{"results": [{"id": "comment-1", "rich_text": [{"plain_text": "Ready for review"}]}], "has_more": false}`))
+	}))
+	defer notionServer.Close()
+
+	state, err := LoadSyncState(filepath.Join(t.TempDir(), "state.json"), nil)
+	if err != nil {
+		t.Fatalf("Error loading state: %v", err)
+	}
+
+	jira := newTestJiraClient(t, jiraServer.URL)
+	notion := newTestNotionClient(notionServer.URL)
+	for run := 0; run < 2; run++ {
+		if _, err := PushNotionComments(context.Background(), jira, notion, "page-1", "TU-1", state); err != nil {
+			t.Fatalf("Error pushing comments: %v", err)
+		}
+	}
+
+	if posts != 1 {
+		t.Errorf("Expected the comment to be posted once, got %d posts", posts)
+	}
+
+	if _, err := PushNotionComments(context.Background(), jira, notion, "page-1", "TU-1", nil); err == nil {
+		t.Errorf("Expected an error without a SyncState")
+	}
+	if posts != 1 {
+		t.Errorf("Expected nothing to be posted without a SyncState, got %d posts", posts)
+	}
+}
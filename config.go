@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is everything a sync run needs besides credentials, which stay in
+// the environment so config files can be committed.
+type Config struct {
+	JQL        string `json:"jql" yaml:"jql"`
+	DatabaseID string `json:"databaseId" yaml:"databaseId"`
+	// Mapping defaults to defaultFieldMapping when empty.
+	Mapping    []FieldMapping `json:"mapping" yaml:"mapping"`
+	RateLimits RateLimits     `json:"rateLimits" yaml:"rateLimits"`
+	// NotionUsers maps Jira account ids to Notion user ids; see
+	// NotionUserIDs.
+	NotionUsers map[string]string `json:"notionUsers" yaml:"notionUsers"`
+}
+
+// RateLimits sets the requests per second sent to each service. Zero keeps
+// the client's default.
+type RateLimits struct {
+	Jira   float64 `json:"jira" yaml:"jira"`
+	Notion float64 `json:"notion" yaml:"notion"`
+}
+
+// LoadConfig reads a Config from a .yaml, .yml or .json file and checks it,
+// reporting every missing or invalid setting at once.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var cfg Config
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &cfg)
+	case ".json":
+		err = json.Unmarshal(data, &cfg)
+	default:
+		return Config{}, fmt.Errorf("config %s: unsupported extension %q; use .yaml, .yml or .json", path, ext)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	if len(cfg.Mapping) == 0 {
+		cfg.Mapping = defaultFieldMapping
+	}
+
+	if err := cfg.validate(); err != nil {
+		return Config{}, fmt.Errorf("config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (cfg Config) validate() error {
+	var problems []error
+	if cfg.DatabaseID == "" {
+		problems = append(problems, errors.New("databaseId is required"))
+	}
+
+	for i, m := range cfg.Mapping {
+		if m.JiraField == "" {
+			problems = append(problems, fmt.Errorf("mapping[%d]: jiraField is required", i))
+		}
+		if m.NotionProperty == "" {
+			problems = append(problems, fmt.Errorf("mapping[%d]: notionProperty is required", i))
+		}
+		switch {
+		case m.NotionType == "":
+			problems = append(problems, fmt.Errorf("mapping[%d]: notionType is required", i))
+		case !supportedNotionType(m.NotionType):
+			problems = append(problems, fmt.Errorf("mapping[%d]: unsupported notionType %q", i, m.NotionType))
+		}
+	}
+
+	if cfg.RateLimits.Jira < 0 || cfg.RateLimits.Notion < 0 {
+		problems = append(problems, errors.New("rateLimits must not be negative"))
+	}
+
+	return errors.Join(problems...)
+}
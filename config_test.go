@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	configs := map[string]string{
+		"sync.yaml": `
+jql: project = TU ORDER BY updated
+databaseId: db-1
+mapping:
+  - jiraField: key
+    notionProperty: Jira Key
+    notionType: rich_text
+  - jiraField: story_points
+    notionProperty: Points
+    notionType: number
+rateLimits:
+  jira: 5
+  notion: 2.5
+`,
+		"sync.json": `{
+	"jql": "project = TU ORDER BY updated",
+	"databaseId": "db-1",
+	"mapping": [
+		{"jiraField": "key", "notionProperty": "Jira Key", "notionType": "rich_text"},
+		{"jiraField": "story_points", "notionProperty": "Points", "notionType": "number"}
+	],
+	"rateLimits": {"jira": 5, "notion": 2.5}
+}`,
+	}
+
+	for name, content := range configs {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := LoadConfig(writeConfig(t, name, content))
+			if err != nil {
+				t.Fatalf("Error loading config: %v", err)
+			}
+
+			if cfg.JQL != "project = TU ORDER BY updated" || cfg.DatabaseID != "db-1" {
+				t.Errorf("Expected the JQL and database id to be read, got %+v", cfg)
+			}
+
+			expected := []FieldMapping{{JiraField: "key", NotionProperty: "Jira Key", NotionType: NotionRichText}, {JiraField: "story_points", NotionProperty: "Points", NotionType: NotionNumber}}
+			if !reflect.DeepEqual(cfg.Mapping, expected) {
+				t.Errorf("Expected mapping %v, got %v", expected, cfg.Mapping)
+			}
+
+			if cfg.RateLimits.Jira != 5 || cfg.RateLimits.Notion != 2.5 {
+				t.Errorf("Expected rate limits 5 and 2.5, got %+v", cfg.RateLimits)
+			}
+		})
+	}
+}
+
+func TestLoadConfigDefaultsMapping(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, "sync.yml", "databaseId: db-1\n"))
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+
+	if len(cfg.Mapping) != len(defaultFieldMapping) {
+		t.Errorf("Expected the default mapping, got %v", cfg.Mapping)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	_, err := LoadConfig(writeConfig(t, "sync.yaml", `
+mapping:
+  - jiraField: summary
+    notionType: formula
+`))
+	if err == nil {
+		t.Fatal("Expected an invalid config to be rejected")
+	}
+
+	for _, expected := range []string{"databaseId is required", "mapping[0]: notionProperty is required", `mapping[0]: unsupported notionType "formula"`} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error to contain %q, got %v", expected, err)
+		}
+	}
+
+	if _, err := LoadConfig(writeConfig(t, "sync.toml", "")); err == nil || !strings.Contains(err.Error(), "unsupported extension") {
+		t.Errorf("Expected an unsupported extension error, got %v", err)
+	}
+}
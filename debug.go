@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// redactedHeaders carry credentials and are never logged verbatim.
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// LoggingTransport logs every request it sends and the response it gets
+// back at debug level, with credentials redacted. It is meant for diagnosing
+// a misbehaving sync without a proxy.
+type LoggingTransport struct {
+	// Base sends the requests. Nil uses http.DefaultTransport.
+	Base   http.RoundTripper
+	Logger *slog.Logger
+}
+
+func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	logger := t.Logger
+	if logger == nil {
+		logger = discardLogger
+	}
+
+	logger.Debug("http request", "method", req.Method, "url", req.URL.String(), "headers", redactHeaders(req.Header))
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		logger.Debug("http request failed", "method", req.Method, "url", req.URL.String(), "duration", time.Since(start), "error", err)
+		return nil, err
+	}
+
+	logger.Debug("http response", "method", req.Method, "url", req.URL.String(), "status", resp.StatusCode, "duration", time.Since(start))
+	return resp, nil
+}
+
+// redactHeaders flattens header for logging, replacing credentials and
+// anything that looks like a token with "REDACTED".
+func redactHeaders(header http.Header) map[string]string {
+	flat := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		if isSecretHeader(name) {
+			value = "REDACTED"
+		}
+		flat[name] = value
+	}
+	return flat
+}
+
+func isSecretHeader(name string) bool {
+	for _, redacted := range redactedHeaders {
+		if strings.EqualFold(name, redacted) {
+			return true
+		}
+	}
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "token") || strings.Contains(lower, "secret")
+}
+
+// withDebugLogging returns a copy of client whose transport is wrapped in a
+// LoggingTransport. A nil client starts from a default one.
+func withDebugLogging(client *http.Client, logger *slog.Logger) *http.Client {
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	wrapped := *client
+	wrapped.Transport = &LoggingTransport{Base: client.Transport, Logger: logger}
+	return &wrapped
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggingTransportRedactsAuthorization(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := withDebugLogging(nil, logger)
+
+	req, err := http.NewRequest("GET", ts.URL+"/rest/api/2/myself", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	req.Header.Set("Authorization", "Basic c2VjcmV0LWNyZWRz")
+	req.Header.Set("X-Proxy-Token", "proxy-secret")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Error sending request: %v", err)
+	}
+	resp.Body.Close()
+
+	output := buf.String()
+	for _, secret := range []string{"c2VjcmV0LWNyZWRz", "proxy-secret"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("Expected %s to be redacted, got %s", secret, output)
+		}
+	}
+
+	for _, expected := range []string{"Authorization:REDACTED", "method=GET", "/rest/api/2/myself", "status=418", "duration=", "Accept:application/json"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected log to contain %q, got %s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Export formats understood by ExportIssues.
+const (
+	ExportJSON = "json"
+	ExportCSV  = "csv"
+)
+
+// ExportIssues writes issues to w for auditing. ExportJSON writes the issues
+// as Jira sent them; ExportCSV writes a header row and one row per issue with
+// its key, summary, status, assignee and updated time.
+func ExportIssues(issues []Issue, w io.Writer, format string) error {
+	switch format {
+	case ExportJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(issues)
+	case ExportCSV:
+		return exportIssuesCSV(issues, w)
+	}
+	return fmt.Errorf("unknown export format %q (expected %s or %s)", format, ExportJSON, ExportCSV)
+}
+
+func exportIssuesCSV(issues []Issue, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"key", "summary", "status", "assignee", "updated"}); err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		row := []string{issue.Key, issue.Fields.Summary, issue.StatusName(), issue.AssigneeName(), issue.Fields.Updated}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
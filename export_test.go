@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExportIssuesCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportIssues([]Issue{testIssue()}, &buf, ExportCSV); err != nil {
+		t.Fatalf("Error exporting issues: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a header and one row, got %q", buf.String())
+	}
+
+	if lines[0] != "key,summary,status,assignee,updated" {
+		t.Errorf("Expected header %q, got %q", "key,summary,status,assignee,updated", lines[0])
+	}
+
+	issue := testIssue()
+	expected := strings.Join([]string{issue.Key, issue.Fields.Summary, issue.StatusName(), issue.AssigneeName(), issue.Fields.Updated}, ",")
+	if lines[1] != expected {
+		t.Errorf("Expected row %q, got %q", expected, lines[1])
+	}
+}
+
+func TestExportIssuesJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportIssues([]Issue{testIssue()}, &buf, ExportJSON); err != nil {
+		t.Fatalf("Error exporting issues: %v", err)
+	}
+
+	var issues []Issue
+	if err := json.Unmarshal(buf.Bytes(), &issues); err != nil {
+		t.Fatalf("Error decoding export: %v", err)
+	}
+
+	if len(issues) != 1 || issues[0].Key != testIssue().Key {
+		t.Errorf("Expected the exported issue to round-trip, got %+v", issues)
+	}
+}
+
+func TestExportIssuesUnknownFormat(t *testing.T) {
+	if err := ExportIssues(nil, &bytes.Buffer{}, "xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// JiraField describes a system or custom field as listed by /rest/api/2/field.
+type JiraField struct {
+	ID     string           `json:"id"`
+	Name   string           `json:"name"`
+	Custom bool             `json:"custom"`
+	Schema *JiraFieldSchema `json:"schema,omitempty"`
+}
+
+type JiraFieldSchema struct {
+	Type   string `json:"type"`
+	Custom string `json:"custom,omitempty"`
+}
+
+// Fields returns every field defined on the instance. The list is fetched
+// once and reused for FieldsCacheTTL; concurrent callers wait for a single
+// fetch rather than each making their own. Failed fetches aren't cached.
+func (c *JiraClient) Fields(ctx context.Context) ([]JiraField, error) {
+	c.fieldsMu.Lock()
+	defer c.fieldsMu.Unlock()
+
+	if c.fields != nil && (c.FieldsCacheTTL == 0 || time.Since(c.fieldsFetchedAt) < c.FieldsCacheTTL) {
+		return c.fields, nil
+	}
+
+	resp, err := c.do(ctx, "GET", joinURL(c.baseURL, "/rest/api/2/field"), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpError(resp.StatusCode, readErrorBody(resp))
+	}
+
+	fields := []JiraField{}
+	if err := c.decode(resp, &fields); err != nil {
+		return nil, err
+	}
+
+	c.fields = fields
+	c.fieldsFetchedAt = time.Now()
+	return fields, nil
+}
+
+// GetCustomFieldID resolves a custom field's display name, such as "Story
+// Points", to its instance-specific id. Names are matched case-insensitively
+// and must identify exactly one custom field.
+func (c *JiraClient) GetCustomFieldID(ctx context.Context, fieldName string) (string, error) {
+	fields, err := c.Fields(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	for _, f := range fields {
+		if f.Custom && strings.EqualFold(f.Name, fieldName) {
+			matches = append(matches, f.ID)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no custom field named %q", fieldName)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("custom field name %q is ambiguous: matches %s", fieldName, strings.Join(matches, ", "))
+}
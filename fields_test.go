@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fieldsServer(t *testing.T, requests *int) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.URL.Path != "/rest/api/2/field" {
+			t.Errorf("Expected path to be %s, got %s", "/rest/api/2/field", r.URL.Path)
+		}
+
+		fields := []JiraField{
+			{ID: "summary", Name: "Summary"},
+			{ID: "customfield_10506", Name: "Bet", Custom: true},
+			{ID: "customfield_10016", Name: "Story Points", Custom: true},
+			{ID: "customfield_10020", Name: "Team", Custom: true},
+			{ID: "customfield_10021", Name: "Team", Custom: true},
+		}
+		err := json.NewEncoder(w).Encode(fields)
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+}
+
+func TestGetCustomFieldID(t *testing.T) {
+	requests := 0
+	ts := fieldsServer(t, &requests)
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+
+	id, err := client.GetCustomFieldID(context.Background(), "story points")
+	if err != nil {
+		t.Fatalf("Error resolving field: %v", err)
+	}
+	if id != "customfield_10016" {
+		t.Errorf("Expected id %s, got %s", "customfield_10016", id)
+	}
+
+	id, err = client.GetCustomFieldID(context.Background(), "Bet")
+	if err != nil {
+		t.Fatalf("Error resolving field: %v", err)
+	}
+	if id != "customfield_10506" {
+		t.Errorf("Expected id %s, got %s", "customfield_10506", id)
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected the field list to be fetched once, got %d requests", requests)
+	}
+}
+
+func TestGetCustomFieldIDErrors(t *testing.T) {
+	requests := 0
+	ts := fieldsServer(t, &requests)
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+
+	if _, err := client.GetCustomFieldID(context.Background(), "Summary"); err == nil || !strings.Contains(err.Error(), "no custom field") {
+		t.Errorf("Expected a no-match error for a system field, got %v", err)
+	}
+
+	_, err := client.GetCustomFieldID(context.Background(), "Team")
+	if err == nil || !strings.Contains(err.Error(), "customfield_10020, customfield_10021") {
+		t.Errorf("Expected an ambiguity error listing both ids, got %v", err)
+	}
+}
+
+func TestGetCustomFieldIDConcurrent(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`[{"id": "customfield_10016", "name": "Story Points", "custom": true}]`))
+	}))
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if id, err := client.GetCustomFieldID(context.Background(), "Story Points"); err != nil || id != "customfield_10016" {
+				t.Errorf("Expected customfield_10016, got %q and %v", id, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("Expected the field list to be fetched once, got %d requests", got)
+	}
+}
+
+func TestFieldsCacheTTL(t *testing.T) {
+	requests := 0
+	ts := fieldsServer(t, &requests)
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+	client.FieldsCacheTTL = time.Nanosecond
+
+	for range 2 {
+		time.Sleep(time.Millisecond)
+		if _, err := client.Fields(context.Background()); err != nil {
+			t.Fatalf("Error fetching fields: %v", err)
+		}
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected an expired cache to be refetched, got %d requests", requests)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// These helpers build the payload shape Jira expects for each kind of custom
+// field, for use as the value passed to UpdateCustomField.
+
+// CustomFieldOption selects a single-select or radio option by id.
+func CustomFieldOption(id string) interface{} {
+	return map[string]string{"id": id}
+}
+
+// CustomFieldOptions selects the given options of a multi-select field.
+func CustomFieldOptions(ids ...string) interface{} {
+	options := make([]map[string]string, len(ids))
+	for i, id := range ids {
+		options[i] = map[string]string{"id": id}
+	}
+	return options
+}
+
+// CustomFieldCascading selects a cascading select's parent option by id and,
+// unless childID is "", one of its child options. A child needs a parent.
+func CustomFieldCascading(parentID, childID string) (interface{}, error) {
+	if parentID == "" {
+		return nil, errors.New("cascading select needs a parent option")
+	}
+
+	value := map[string]interface{}{"id": parentID}
+	if childID != "" {
+		value["child"] = map[string]string{"id": childID}
+	}
+	return value, nil
+}
+
+// CustomFieldUser sets a user picker field by Atlassian account id.
+func CustomFieldUser(accountID string) interface{} {
+	return map[string]string{"accountId": accountID}
+}
+
+// CustomFieldDate sets a date picker field. Only the calendar date of t is
+// sent, so pass t in the location whose date is meant.
+func CustomFieldDate(t time.Time) interface{} {
+	return t.Format("2006-01-02")
+}
+
+// CustomFieldText sets a single-line or paragraph text field.
+func CustomFieldText(s string) interface{} {
+	return s
+}
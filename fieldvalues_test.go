@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCustomFieldValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"option", CustomFieldOption("11755"), `{"id":"11755"}`},
+		{"options", CustomFieldOptions("1", "2"), `[{"id":"1"},{"id":"2"}]`},
+		{"no options", CustomFieldOptions(), `[]`},
+		{"user", CustomFieldUser("5b10a2844c20165700ede21g"), `{"accountId":"5b10a2844c20165700ede21g"}`},
+		{"date", CustomFieldDate(time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)), `"2024-03-01"`},
+		{"text", CustomFieldText(`Say "hi"`), `"Say \"hi\""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("Error marshalling value: %v", err)
+			}
+
+			if string(data) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, data)
+			}
+		})
+	}
+}
+
+func TestCustomFieldCascading(t *testing.T) {
+	value, err := CustomFieldCascading("10000", "10001")
+	if err != nil {
+		t.Fatalf("Error building value: %v", err)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Error marshalling value: %v", err)
+	}
+	if expected := `{"child":{"id":"10001"},"id":"10000"}`; string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+
+	value, err = CustomFieldCascading("10000", "")
+	if err != nil {
+		t.Fatalf("Error building value: %v", err)
+	}
+	data, _ = json.Marshal(value)
+	if expected := `{"id":"10000"}`; string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+
+	if _, err := CustomFieldCascading("", "10001"); err == nil {
+		t.Errorf("Expected an error for a child without a parent")
+	}
+}
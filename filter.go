@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// FilterJQL returns the JQL of the saved filter filterID. It is fetched once
+// per client and reused, so a run sees one consistent query. A filter that
+// was deleted or isn't shared with the authenticated user is reported as
+// such.
+func (c *JiraClient) FilterJQL(ctx context.Context, filterID string) (string, error) {
+	if _, err := strconv.ParseUint(filterID, 10, 64); err != nil {
+		return "", fmt.Errorf("invalid Jira filter id %q: must be numeric", filterID)
+	}
+
+	c.filtersMu.Lock()
+	defer c.filtersMu.Unlock()
+	if jql, ok := c.filters[filterID]; ok {
+		return jql, nil
+	}
+
+	resp, err := c.do(ctx, "GET", joinURL(c.baseURL, "/rest/api/2/filter/"+filterID), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := httpError(resp.StatusCode, readErrorBody(resp))
+		if resp.StatusCode == http.StatusNotFound || errors.Is(err, ErrForbidden) {
+			return "", fmt.Errorf("Jira filter %s doesn't exist or isn't shared with this user: %w", filterID, err)
+		}
+		return "", err
+	}
+
+	var filter struct {
+		JQL string `json:"jql"`
+	}
+	if err := c.decode(resp, &filter); err != nil {
+		return "", err
+	}
+	if filter.JQL == "" {
+		return "", fmt.Errorf("Jira filter %s has no JQL", filterID)
+	}
+
+	if c.filters == nil {
+		c.filters = map[string]string{}
+	}
+	c.filters[filterID] = filter.JQL
+	return filter.JQL, nil
+}
+
+// FetchFilterIssues is FetchIssues for the JQL of the saved filter filterID.
+func (c *JiraClient) FetchFilterIssues(ctx context.Context, filterID string, opts ...FetchOptions) (FetchResult, error) {
+	jql, err := c.FilterJQL(ctx, filterID)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	return c.FetchIssues(ctx, jql, opts...)
+}
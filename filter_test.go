@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchFilterIssues(t *testing.T) {
+	filterRequests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/2/filter/10200":
+			filterRequests++
+			w.Write([]byte(`{"id": "10200", "name": "Growth bets", "jql": "project = TU AND labels = growth"}`))
+		case "/rest/api/2/search":
+			if got := r.URL.Query().Get("jql"); got != "project = TU AND labels = growth" {
+				t.Errorf("Expected the filter's JQL, got %q", got)
+			}
+			if err := json.NewEncoder(w).Encode(IssueResponse{Total: 1, Issues: []Issue{{Key: "TU-1"}}}); err != nil {
+				t.Fatalf("Error encoding response: %v", err)
+			}
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+	for range 2 {
+		result, err := client.FetchFilterIssues(context.Background(), "10200")
+		if err != nil {
+			t.Fatalf("Error fetching filter issues: %v", err)
+		}
+		if len(result.Issues) != 1 || result.Issues[0].Key != "TU-1" {
+			t.Errorf("Expected TU-1, got %+v", result.Issues)
+		}
+	}
+
+	if filterRequests != 1 {
+		t.Errorf("Expected the filter to be resolved once, got %d requests", filterRequests)
+	}
+}
+
+func TestFilterJQLErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errorMessages":["The selected filter is not available to you, perhaps it has been deleted or had its permissions changed."]}`))
+	}))
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+
+	if _, err := client.FilterJQL(context.Background(), "404"); err == nil || !strings.Contains(err.Error(), "filter 404 doesn't exist or isn't shared") {
+		t.Errorf("Expected a missing filter error, got %v", err)
+	}
+
+	if _, err := client.FilterJQL(context.Background(), "../search"); err == nil || !strings.Contains(err.Error(), "must be numeric") {
+		t.Errorf("Expected an invalid filter id error, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Ping checks that Jira is reachable and accepts the client's credentials,
+// returning the display name of the authenticated user.
+func (c *JiraClient) Ping(ctx context.Context) (string, error) {
+	resp, err := c.do(ctx, "GET", joinURL(c.baseURL, "/rest/api/2/myself"), nil)
+	if err != nil {
+		return "", fmt.Errorf("connecting to Jira: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("checking Jira credentials: %w", httpError(resp.StatusCode, readErrorBody(resp)))
+	}
+
+	var user User
+	if err := c.decode(resp, &user); err != nil {
+		return "", err
+	}
+
+	return user.DisplayName, nil
+}
+
+// Ping checks that Notion is reachable and accepts the integration token,
+// returning the name of the integration's bot user.
+func (c *NotionClient) Ping(ctx context.Context) (string, error) {
+	var user struct {
+		Name string `json:"name"`
+	}
+	if _, err := c.do(ctx, "GET", c.baseURL+"/users/me", nil, &user); err != nil {
+		return "", fmt.Errorf("checking Notion token: %w", err)
+	}
+
+	return user.Name, nil
+}
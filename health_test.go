@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJiraPing(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/myself" {
+			t.Errorf("Expected path to be %s, got %s", "/rest/api/2/myself", r.URL.Path)
+		}
+		w.Write([]byte(`{"accountId": "abc", "displayName": "Sync Bot"}`))
+	}))
+	defer ts.Close()
+
+	name, err := newTestJiraClient(t, ts.URL).Ping(context.Background())
+	if err != nil {
+		t.Fatalf("Error pinging Jira: %v", err)
+	}
+
+	if name != "Sync Bot" {
+		t.Errorf("Expected display name %s, got %s", "Sync Bot", name)
+	}
+}
+
+func TestNotionPing(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/users/me" {
+			t.Errorf("Expected GET /users/me, got %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"object": "user", "type": "bot", "name": "Jira Sync"}`))
+	}))
+	defer ts.Close()
+
+	name, err := newTestNotionClient(ts.URL).Ping(context.Background())
+	if err != nil {
+		t.Fatalf("Error pinging Notion: %v", err)
+	}
+
+	if name != "Jira Sync" {
+		t.Errorf("Expected name %s, got %s", "Jira Sync", name)
+	}
+}
+
+func TestPingUnauthorized(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	if _, err := newTestJiraClient(t, ts.URL).Ping(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected Jira ping to fail with ErrUnauthorized, got %v", err)
+	}
+
+	if _, err := newTestNotionClient(ts.URL).Ping(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected Notion ping to fail with ErrUnauthorized, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+// PageOptions sets how a page looks in Notion, beyond its properties.
+type PageOptions struct {
+	// Icon is an emoji. Empty leaves the icon alone.
+	Icon string
+	// Cover is the URL of an external image. Empty leaves the cover alone.
+	Cover string
+}
+
+// IssueTypeIcons maps Jira issue type names to page icons. Callers may
+// replace or extend it; types not listed get DefaultIssueTypeIcon.
+var IssueTypeIcons = map[string]string{
+	"Bug":      "🐛",
+	"Story":    "📗",
+	"Task":     "✅",
+	"Sub-task": "🔹",
+	"Epic":     "⚡",
+}
+
+// DefaultIssueTypeIcon is the icon for issue types missing from
+// IssueTypeIcons.
+const DefaultIssueTypeIcon = "📄"
+
+// IssueTypeCovers optionally maps Jira issue type names to cover image URLs.
+// It is empty by default, so pages get no cover.
+var IssueTypeCovers = map[string]string{}
+
+// issuePageOptions picks issue's icon and cover by its type.
+func issuePageOptions(issue Issue) PageOptions {
+	issueType := issue.IssueTypeName()
+
+	icon, ok := IssueTypeIcons[issueType]
+	if !ok {
+		icon = DefaultIssueTypeIcon
+	}
+	return PageOptions{Icon: icon, Cover: IssueTypeCovers[issueType]}
+}
+
+func pageOptions(opts []PageOptions) PageOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return PageOptions{}
+}
+
+// apply adds the icon and cover to a create or update payload.
+func (o PageOptions) apply(payload map[string]interface{}) {
+	if o.Icon != "" {
+		payload["icon"] = map[string]interface{}{"type": "emoji", "emoji": o.Icon}
+	}
+	if o.Cover != "" {
+		payload["cover"] = map[string]interface{}{"type": "external", "external": map[string]interface{}{"url": o.Cover}}
+	}
+}
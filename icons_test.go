@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreatePageIconForIssueType(t *testing.T) {
+	var payload map[string]json.RawMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("Error decoding request: %v", err)
+		}
+		w.Write([]byte(`{"id": "page-1"}`))
+	}))
+	defer ts.Close()
+
+	bug := Issue{Key: "TU-1", Fields: IssueFields{IssueType: &IssueType{Name: "Bug"}}}
+	if _, err := newTestNotionClient(ts.URL).CreatePage(context.Background(), "db-1", map[string]interface{}{}, issuePageOptions(bug)); err != nil {
+		t.Fatalf("Error creating page: %v", err)
+	}
+
+	assertJSON(t, payload["icon"], `{"type":"emoji","emoji":"🐛"}`)
+	if _, ok := payload["cover"]; ok {
+		t.Errorf("Expected no cover, got %s", payload["cover"])
+	}
+}
+
+func TestIssuePageOptions(t *testing.T) {
+	if got := issuePageOptions(Issue{Fields: IssueFields{IssueType: &IssueType{Name: "Spike"}}}).Icon; got != DefaultIssueTypeIcon {
+		t.Errorf("Expected the default icon for an unknown type, got %s", got)
+	}
+
+	IssueTypeCovers["Epic"] = "https://example.com/epic.png"
+	defer delete(IssueTypeCovers, "Epic")
+
+	payload := map[string]interface{}{}
+	issuePageOptions(Issue{Fields: IssueFields{IssueType: &IssueType{Name: "Epic"}}}).apply(payload)
+	assertJSON(t, payload, `{"icon":{"type":"emoji","emoji":"⚡"},"cover":{"type":"external","external":{"url":"https://example.com/epic.png"}}}`)
+}
@@ -0,0 +1,62 @@
+package main
+
+import "strings"
+
+// IssueLink is one entry of an issue's issuelinks field. Only the issue at
+// the other end of the link is set: OutwardIssue when this issue is the
+// subject of Type.Outward ("TU-1 blocks TU-2"), InwardIssue when it is the
+// subject of Type.Inward ("TU-1 is blocked by TU-2").
+type IssueLink struct {
+	Type         IssueLinkType `json:"type"`
+	InwardIssue  *LinkedIssue  `json:"inwardIssue,omitempty"`
+	OutwardIssue *LinkedIssue  `json:"outwardIssue,omitempty"`
+}
+
+// IssueLinkType names a kind of link and how it reads in each direction,
+// e.g. "Blocks", "is blocked by" and "blocks".
+type IssueLinkType struct {
+	Name    string `json:"name"`
+	Inward  string `json:"inward"`
+	Outward string `json:"outward"`
+}
+
+type LinkedIssue struct {
+	Key string `json:"key"`
+}
+
+// IssueRelation is a link as seen from the issue that has it: Relation is
+// how it reads from this issue to Key, such as "blocks", and Outward is
+// whether that is the link type's outward direction.
+type IssueRelation struct {
+	Type     string
+	Relation string
+	Key      string
+	Outward  bool
+}
+
+// LinkedIssues returns the issue's links, as decoded from issuelinks.
+func (i Issue) LinkedIssues() []IssueRelation {
+	var relations []IssueRelation
+	for _, link := range i.Fields.IssueLinks {
+		switch {
+		case link.OutwardIssue != nil:
+			relations = append(relations, IssueRelation{Type: link.Type.Name, Relation: link.Type.Outward, Key: link.OutwardIssue.Key, Outward: true})
+		case link.InwardIssue != nil:
+			relations = append(relations, IssueRelation{Type: link.Type.Name, Relation: link.Type.Inward, Key: link.InwardIssue.Key})
+		}
+	}
+	return relations
+}
+
+// LinkedKeys returns the keys of the issues linked by relation, such as
+// "blocks" or "is blocked by", ignoring case. This is what the
+// "links:<relation>" mapping field reads.
+func (i Issue) LinkedKeys(relation string) []string {
+	keys := []string{}
+	for _, linked := range i.LinkedIssues() {
+		if strings.EqualFold(linked.Relation, relation) {
+			keys = append(keys, linked.Key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+const linkedIssueJSON = `{"key": "TU-1", "fields": {"issuelinks": [
+	{"id": "10001", "type": {"id": "10000", "name": "Blocks", "inward": "is blocked by", "outward": "blocks"}, "outwardIssue": {"id": "10002", "key": "TU-2"}},
+	{"id": "10003", "type": {"id": "10000", "name": "Blocks", "inward": "is blocked by", "outward": "blocks"}, "inwardIssue": {"id": "10004", "key": "TU-4"}},
+	{"id": "10005", "type": {"id": "10000", "name": "Blocks", "inward": "is blocked by", "outward": "blocks"}, "outwardIssue": {"id": "20001", "key": "OPS-9"}}
+]}}`
+
+func TestLinkedIssues(t *testing.T) {
+	var issue Issue
+	if err := json.Unmarshal([]byte(linkedIssueJSON), &issue); err != nil {
+		t.Fatalf("Error decoding issue: %v", err)
+	}
+
+	links := issue.LinkedIssues()
+	if len(links) != 3 {
+		t.Fatalf("Expected 3 links, got %+v", links)
+	}
+	if links[0] != (IssueRelation{Type: "Blocks", Relation: "blocks", Key: "TU-2", Outward: true}) {
+		t.Errorf("Expected TU-1 to block TU-2, got %+v", links[0])
+	}
+	if links[1] != (IssueRelation{Type: "Blocks", Relation: "is blocked by", Key: "TU-4"}) {
+		t.Errorf("Expected TU-1 to be blocked by TU-4, got %+v", links[1])
+	}
+
+	if keys := issue.LinkedKeys("Blocks"); len(keys) != 2 || keys[0] != "TU-2" || keys[1] != "OPS-9" {
+		t.Errorf("Expected TU-1 to block TU-2 and OPS-9, got %v", keys)
+	}
+}
+
+func TestResolveRelationsLinks(t *testing.T) {
+	var issue Issue
+	if err := json.Unmarshal([]byte(linkedIssueJSON), &issue); err != nil {
+		t.Fatalf("Error decoding issue: %v", err)
+	}
+
+	// OPS-9 is outside the synced JQL and has no page.
+	notion := &fakeNotion{pages: map[string]string{"TU-2": "page-TU-2", "TU-4": "page-TU-4"}}
+	server := httptest.NewServer(notion.handler(t))
+	defer server.Close()
+
+	mapping := []FieldMapping{
+		{JiraField: "links:blocks", NotionProperty: "Blocks", NotionType: NotionRelation},
+		{JiraField: "links:is blocked by", NotionProperty: "Blocked By", NotionType: NotionRelation},
+	}
+	properties, missing, err := resolveRelations(context.Background(), newTestNotionClient(server.URL), "db-1", issue, mapping)
+	if err != nil {
+		t.Fatalf("Error resolving relations: %v", err)
+	}
+
+	assertJSON(t, properties, `{
+		"Blocked By": {"relation": [{"id": "page-TU-4"}]},
+		"Blocks": {"relation": [{"id": "page-TU-2"}]}
+	}`)
+	if len(missing) != 1 || missing[0] != "OPS-9" {
+		t.Errorf("Expected the OPS-9 page to be missing, got %v", missing)
+	}
+}
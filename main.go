@@ -1,164 +1,1585 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
-	"net/http/httptest"
+	"net/url"
 	"os"
-	"testing"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/time/rate"
 )
 
-var baseURL string
+const (
+	// defaultJQL selects the TypeUp epics that back the bets in Notion.
+	defaultJQL = "project = TU AND issuetype = Epic"
 
-func TestFetchIssues(t *testing.T) {
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		response := IssueResponse{Issues: []Issue{{Key: "TU-1"}, {Key: "TU-2"}}}
-		err := json.NewEncoder(w).Encode(response)
-		if err != nil {
-			t.Fatalf("Error encoding response: %v", err)
+	// defaultPageSize matches the page size Jira uses when maxResults is
+	// omitted from a search request.
+	defaultPageSize = 50
+
+	// defaultHTTPTimeout bounds every request made by a client built without
+	// an explicit *http.Client.
+	defaultHTTPTimeout = 30 * time.Second
+
+	defaultMaxRetries     = 3
+	defaultRetryBaseDelay = 500 * time.Millisecond
+
+	// defaultFieldsCacheTTL keeps the field list for a typical run while
+	// still noticing fields added on a long-lived webhook server.
+	defaultFieldsCacheTTL = 15 * time.Minute
+
+	// defaultRateLimitThreshold leaves a little headroom for other clients
+	// sharing the same Jira quota.
+	defaultRateLimitThreshold = 5
+
+	// defaultJiraRateLimit is a conservative requests-per-second ceiling that
+	// keeps batch syncs well under Jira Cloud's quotas.
+	defaultJiraRateLimit = 10
+
+	// maxErrorBodySize caps how much of an error response is copied into the
+	// returned error, so large HTML error pages don't flood the logs.
+	maxErrorBodySize = 8 << 10
+
+	// defaultMaxResponseSize is far above a page of 100 issues with every
+	// field, but stops a runaway response before it exhausts memory.
+	defaultMaxResponseSize = 64 << 20
+)
+
+type Issue struct {
+	// ID is Jira's numeric issue id. Unlike Key it survives a move to
+	// another project; see NotionClient.JiraIDProperty.
+	ID     string      `json:"id,omitempty"`
+	Key    string      `json:"key"`
+	Fields IssueFields `json:"fields"`
+	// RenderedFields and Changelog are only sent when requested with
+	// FetchOptions.Expand.
+	RenderedFields map[string]json.RawMessage `json:"renderedFields,omitempty"`
+	Changelog      *Changelog                 `json:"changelog,omitempty"`
+}
+
+// Changelog is an issue's change history, as returned by expand=changelog.
+type Changelog struct {
+	StartAt    int             `json:"startAt"`
+	MaxResults int             `json:"maxResults"`
+	Total      int             `json:"total"`
+	Histories  []ChangeHistory `json:"histories"`
+}
+
+// ChangeHistory is one edit to an issue, which may change several fields.
+type ChangeHistory struct {
+	ID      string       `json:"id"`
+	Author  *User        `json:"author"`
+	Created string       `json:"created"`
+	Items   []ChangeItem `json:"items"`
+}
+
+// ChangeItem is a single field's change within a ChangeHistory.
+type ChangeItem struct {
+	Field      string `json:"field"`
+	FieldType  string `json:"fieldtype"`
+	From       string `json:"from"`
+	FromString string `json:"fromString"`
+	To         string `json:"to"`
+	ToString   string `json:"toString"`
+}
+
+// IssueFields holds the subset of Jira's issue fields the sync reads. Jira
+// sends null for an unassigned issue or one without a priority, so those are
+// pointers.
+type IssueFields struct {
+	Summary  string    `json:"summary"`
+	Status   *Status   `json:"status"`
+	Assignee *User     `json:"assignee"`
+	Priority *Priority `json:"priority"`
+	Updated  string    `json:"updated"`
+	Labels   []string  `json:"labels"`
+	// Components are only sent when "components" is among the requested
+	// fields.
+	Components []Component `json:"components,omitempty"`
+	// IssueLinks are only sent when "issuelinks" is among the requested
+	// fields; see LinkedIssues.
+	IssueLinks []IssueLink `json:"issuelinks,omitempty"`
+	// DueDate is a YYYY-MM-DD date, or "" when the issue has none.
+	DueDate string `json:"duedate,omitempty"`
+	// IssueType is only sent when "issuetype" is among the requested
+	// fields.
+	IssueType *IssueType `json:"issuetype,omitempty"`
+	// Parent is the epic or parent issue of a sub-task, if any.
+	Parent *IssueParent `json:"parent,omitempty"`
+	// Description is ADF on the v3 API and a plain string on v2; see
+	// adfToNotionBlocks.
+	Description json.RawMessage `json:"description"`
+	// Custom holds every customfield_* value as sent by Jira, keyed by field
+	// id, since their shape depends on how each instance is configured.
+	Custom map[string]json.RawMessage `json:"-"`
+}
+
+// issueFieldsAlias has IssueFields' fields but not its methods, so the
+// (un)marshallers below can use the default behaviour without recursing.
+type issueFieldsAlias IssueFields
+
+func (f *IssueFields) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*issueFieldsAlias)(f)); err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	f.Custom = nil
+	for name, value := range raw {
+		if !strings.HasPrefix(name, "customfield_") {
+			continue
+		}
+		if f.Custom == nil {
+			f.Custom = map[string]json.RawMessage{}
+		}
+		f.Custom[name] = value
+	}
+
+	return nil
+}
+
+func (f IssueFields) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(issueFieldsAlias(f))
+	if err != nil || len(f.Custom) == 0 {
+		return data, err
+	}
+
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for name, value := range f.Custom {
+		merged[name] = value
+	}
+
+	return json.Marshal(merged)
+}
+
+// IssueParent identifies an issue's parent. Jira also sends a summary of the
+// parent's fields, which the sync doesn't need.
+type IssueParent struct {
+	ID  string `json:"id"`
+	Key string `json:"key"`
+}
+
+type IssueType struct {
+	Name string `json:"name"`
+}
+
+type Status struct {
+	Name string `json:"name"`
+}
+
+type User struct {
+	AccountID   string `json:"accountId"`
+	DisplayName string `json:"displayName"`
+}
+
+type Priority struct {
+	Name string `json:"name"`
+}
+
+// Component is one of the project components an issue is filed under.
+type Component struct {
+	Name string `json:"name"`
+}
+
+// jiraTimeLayout is the timestamp format Jira uses for fields like updated.
+const jiraTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+func parseJiraTime(value string) (time.Time, error) {
+	return time.Parse(jiraTimeLayout, value)
+}
+
+// StatusName returns the issue's status, or "" when Jira didn't send one.
+func (i Issue) StatusName() string {
+	if i.Fields.Status == nil {
+		return ""
+	}
+	return i.Fields.Status.Name
+}
+
+// CustomFieldString returns a custom field's value as text. Option fields
+// yield their value, named objects their name, and scalars their JSON text.
+// An unset field yields "".
+func (i Issue) CustomFieldString(fieldID string) string {
+	raw, ok := i.Fields.Custom[fieldID]
+	if !ok || string(raw) == "null" {
+		return ""
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+
+	var object struct {
+		Value string `json:"value"`
+		Name  string `json:"name"`
+	}
+	if err := json.Unmarshal(raw, &object); err == nil {
+		if object.Value != "" {
+			return object.Value
 		}
-	}))
+		return object.Name
+	}
+
+	return string(raw)
+}
+
+// ParentKey returns the key of the issue's parent, or "" when it has none.
+func (i Issue) ParentKey() string {
+	if i.Fields.Parent == nil {
+		return ""
+	}
+	return i.Fields.Parent.Key
+}
+
+// IssueTypeName returns the issue's type, such as "Bug", or "" when Jira
+// didn't send one.
+func (i Issue) IssueTypeName() string {
+	if i.Fields.IssueType == nil {
+		return ""
+	}
+	return i.Fields.IssueType.Name
+}
+
+// EpicLinkFieldID is the legacy "Epic Link" custom field that Jira Server
+// and Data Center use instead of parent for an issue's epic.
+var EpicLinkFieldID = "customfield_10014"
+
+// EpicKey returns the key of the issue's epic: its parent, or failing that
+// the value of the EpicLinkFieldID field. It is "" for issues in no epic.
+func (i Issue) EpicKey() string {
+	if key := i.ParentKey(); key != "" {
+		return key
+	}
+	return i.CustomFieldString(EpicLinkFieldID)
+}
+
+// AssigneeName returns the assignee's display name, or "" when unassigned.
+func (i Issue) AssigneeName() string {
+	if i.Fields.Assignee == nil {
+		return ""
+	}
+	return i.Fields.Assignee.DisplayName
+}
 
-	defer ts.Close()
+// PriorityName returns the issue's priority, or "" when it has none.
+func (i Issue) PriorityName() string {
+	if i.Fields.Priority == nil {
+		return ""
+	}
+	return i.Fields.Priority.Name
+}
 
-	baseURL = ts.URL
+// IssueResponse is one page of search results. v2 search pages with
+// StartAt/MaxResults/Total; v3 search/jql pages with NextPageToken/IsLast.
+type IssueResponse struct {
+	StartAt       int     `json:"startAt"`
+	MaxResults    int     `json:"maxResults"`
+	Total         int     `json:"total"`
+	NextPageToken string  `json:"nextPageToken,omitempty"`
+	IsLast        bool    `json:"isLast,omitempty"`
+	Issues        []Issue `json:"issues"`
+}
+
+// FetchOptions controls where fetchIssues starts reading the search results
+// and how many issues it asks Jira for on each page.
+type FetchOptions struct {
+	StartAt    int
+	MaxResults int
+	// Expand asks Jira for extra data on each issue, such as
+	// "renderedFields" or "changelog". It is omitted from the request when
+	// empty, keeping the payload minimal.
+	Expand []string
+	// Fields limits the issue fields Jira returns. It defaults to
+	// defaultFields; []string{"*all"} asks for every field.
+	Fields []string
+	// Limit stops paging once this many distinct issues are collected, for
+	// smoke tests against large projects. Zero means no limit.
+	Limit int
+	// Cursor resumes a search from a FetchResult.Cursor saved by an earlier
+	// fetch with the same JQL. On v2 it replaces StartAt.
+	Cursor string
+}
 
-	issues, _, err := fetchIssues("encodedCredentials", baseURL)
+// defaultFields is what FetchIssues asks Jira for when FetchOptions.Fields is
+// empty: enough to identify an issue and tell when it last changed.
+var defaultFields = []string{"key", "summary", "status", "updated"}
+
+// setCommonHeaders sets the headers every Jira request carries. Extra headers,
+// such as a proxy token, are applied first so they can't replace the
+// standard ones.
+func setCommonHeaders(req *http.Request, encodedCredentials string, extra map[string]string) {
+	for name, value := range extra {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Authorization", "Basic "+encodedCredentials)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+}
+
+// readErrorBody reads at most maxErrorBodySize bytes of an error response.
+func readErrorBody(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	return string(body)
+}
+
+// ErrResponseTooLarge is wrapped by the error for a response body over a
+// client's MaxResponseSize.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// decodeJSON decodes body into out as it is read, without buffering it
+// whole, and fails with ErrResponseTooLarge once more than maxSize bytes
+// have been read. A maxSize of zero or less means no limit.
+func decodeJSON(body io.Reader, maxSize int64, out interface{}) error {
+	if maxSize <= 0 {
+		return json.NewDecoder(body).Decode(out)
+	}
+
+	limited := &io.LimitedReader{R: body, N: maxSize + 1}
+	err := json.NewDecoder(limited).Decode(out)
+	if limited.N <= 0 {
+		return fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, maxSize)
+	}
+	return err
+}
+
+// decode decodes a Jira response body into out, within MaxResponseSize.
+func (c *JiraClient) decode(resp *http.Response, out interface{}) error {
+	return decodeJSON(resp.Body, c.MaxResponseSize, out)
+}
+
+// ErrUnauthorized, ErrForbidden and ErrNotFound are wrapped by errors for
+// 401, 403 and 404 responses, so callers can detect bad credentials or a
+// missing issue with errors.Is.
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+)
+
+// HTTPError is returned for a non-2xx response from Jira or Notion. Callers
+// can use errors.As to inspect the status and decide whether to retry.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error includes the body as sent, except for a Jira 400 whose messages can
+// be read out of it; see jiraErrorMessage.
+func (e *HTTPError) Error() string {
+	if e.StatusCode == http.StatusBadRequest {
+		if message, ok := jiraErrorMessage(e.Body); ok {
+			return fmt.Sprintf("HTTP error! Status: %d, %s", e.StatusCode, message)
+		}
+	}
+	return fmt.Sprintf("HTTP error! Status: %d, Body: %s", e.StatusCode, e.Body)
+}
+
+// jiraErrorMessage turns a Jira error body, such as
+// {"errorMessages": [], "errors": {"customfield_10506": "Option id 'x' is not valid"}},
+// into "field customfield_10506: Option id 'x' is not valid". Messages are
+// joined with "; ", field errors sorted by field. It reports false when body
+// isn't in that shape or carries no messages.
+func jiraErrorMessage(body string) (string, bool) {
+	var parsed struct {
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+		return "", false
+	}
+
+	messages := append([]string{}, parsed.ErrorMessages...)
+	fields := make([]string, 0, len(parsed.Errors))
+	for field := range parsed.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		messages = append(messages, "field "+field+": "+parsed.Errors[field])
+	}
+
+	if len(messages) == 0 {
+		return "", false
+	}
+	return strings.Join(messages, "; "), true
+}
+
+// Retryable reports whether the request may succeed if sent again later,
+// which is the case for 429 and 5xx responses but not other 4xx ones.
+func (e *HTTPError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Unwrap returns ErrUnauthorized, ErrForbidden or ErrNotFound for 401, 403
+// and 404 responses.
+func (e *HTTPError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	}
+	return nil
+}
+
+func httpError(statusCode int, body string) error {
+	return &HTTPError{StatusCode: statusCode, Body: body}
+}
+
+// logDryRun records a write that was skipped because the client is in dry-run
+// mode, including the exact body that would have been sent.
+func logDryRun(logger *slog.Logger, method, url string, body []byte) {
+	logger.Info("dry run: request not sent", "method", method, "url", url, "body", string(body))
+}
+
+// AuthType selects the Authorization scheme JiraClient sends.
+type AuthType int
+
+const (
+	// BasicAuth sends base64-encoded email:api-token credentials, as Jira
+	// Cloud expects. It is the default.
+	BasicAuth AuthType = iota
+	// BearerAuth sends a personal access token, as Jira Data Center expects.
+	BearerAuth
+)
+
+// JiraClient talks to a single Jira instance, reusing one *http.Client so
+// connections are pooled across requests.
+type JiraClient struct {
+	httpClient *http.Client
+	baseURL    string
+	creds      string
+
+	// AuthType selects how creds are sent. With BearerAuth creds is the raw
+	// personal access token rather than base64-encoded credentials.
+	AuthType AuthType
+	// Headers are added to every request, for example a token required by
+	// an authenticating proxy or X-Atlassian-Token: no-check. They cannot
+	// override Authorization, Accept or Content-Type.
+	Headers map[string]string
+	// MaxRetries is how many times a request is retried after a transient
+	// failure (429, 502, 503, 504). Zero disables retries.
+	MaxRetries int
+	// RetryBaseDelay caps the backoff before the first retry; the cap
+	// doubles on each subsequent attempt and the actual wait is a random
+	// fraction of it. A Retry-After header takes precedence.
+	RetryBaseDelay time.Duration
+	// Rand draws the backoff jitter. Nil uses the global source; set a seeded
+	// one for deterministic tests.
+	Rand *rand.Rand
+	// Logger receives a structured record of every fetch and update. A nil
+	// Logger discards them.
+	Logger *slog.Logger
+	// DryRun logs writes instead of sending them. Reads still hit Jira.
+	DryRun bool
+	// APIVersion selects the search endpoint. Jira Cloud is retiring v2
+	// search, but Jira Server and Data Center only offer v2.
+	APIVersion string
+	// Limiter spaces out every request, including retries and pagination,
+	// to stay under Jira's quotas. A nil Limiter disables rate limiting.
+	Limiter *rate.Limiter
+	// Metrics counts fetches, updates and request latency. A nil Metrics
+	// records nothing.
+	Metrics *Metrics
+	// FieldsCacheTTL is how long the field list fetched by Fields is reused.
+	// Zero caches it for the client's lifetime.
+	FieldsCacheTTL time.Duration
+	// RateLimitThreshold is the X-RateLimit-Remaining below which requests
+	// wait for X-RateLimit-Reset instead of running into a 429. Zero
+	// disables the wait; see RateLimit.
+	RateLimitThreshold int
+	// MaxResponseSize caps the bytes read from a JSON response; a larger
+	// one fails with ErrResponseTooLarge. Zero means no limit.
+	MaxResponseSize int64
+
+	randMu          sync.Mutex
+	rateLimitMu     sync.Mutex
+	rateLimit       RateLimitStatus
+	fieldsMu        sync.Mutex
+	fields          []JiraField
+	fieldsFetchedAt time.Time
+
+	filtersMu sync.Mutex
+	filters   map[string]string
+}
+
+// Jira REST API versions understood by JiraClient.APIVersion.
+const (
+	APIVersion2 = "2"
+	APIVersion3 = "3"
+)
+
+var discardLogger = slog.New(slog.DiscardHandler)
+
+func (c *JiraClient) log() *slog.Logger {
+	if c.Logger == nil {
+		return discardLogger
+	}
+	return c.Logger
+}
+
+// ParseBaseURL checks that raw is an http or https URL with a host and
+// returns it without a trailing slash, so mistakes like a missing https://
+// are caught before any request is built.
+func ParseBaseURL(raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
 	if err != nil {
-		t.Fatalf("Error fetching issues: %v", err)
+		return "", fmt.Errorf("invalid Jira base URL %q: %w", raw, err)
 	}
 
-	expectedIssues := []Issue{{Key: "TU-1"}, {Key: "TU-2"}}
-	if len(issues) != len(expectedIssues) {
-		t.Errorf("Expected %d issues, got %d", len(expectedIssues), len(issues))
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", fmt.Errorf("invalid Jira base URL %q: scheme must be http or https", raw)
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("invalid Jira base URL %q: missing host", raw)
 	}
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", fmt.Errorf("invalid Jira base URL %q: must not have a query or fragment", raw)
+	}
+
+	return strings.TrimRight(parsed.String(), "/"), nil
+}
+
+// NewJiraClient returns a client for baseURL authenticating with the
+// base64-encoded creds. baseURL is validated with ParseBaseURL. A nil
+// httpClient is replaced with one that times out after defaultHTTPTimeout.
+func NewJiraClient(baseURL, creds string, httpClient *http.Client) (*JiraClient, error) {
+	baseURL, err := ParseBaseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	return &JiraClient{
+		httpClient:     httpClient,
+		baseURL:        baseURL,
+		creds:          creds,
+		MaxRetries:     defaultMaxRetries,
+		RetryBaseDelay: defaultRetryBaseDelay,
+		APIVersion:     APIVersion2,
+		Limiter:        rate.NewLimiter(defaultJiraRateLimit, 1),
+		FieldsCacheTTL: defaultFieldsCacheTTL,
+
+		RateLimitThreshold: defaultRateLimitThreshold,
+		MaxResponseSize:    defaultMaxResponseSize,
+	}, nil
+}
 
-	for i, issue := range issues {
-		if issue.Key != expectedIssues[i].Key {
-			t.Errorf("Expected issue %d key to be %s, got %s", i, expectedIssues[i].Key, issue.Key)
+// contentHeaders overrides the JSON Accept and Content-Type headers that
+// setCommonHeaders applies, for endpoints that send or return other media
+// types. Empty fields keep the JSON default.
+type contentHeaders struct {
+	Accept      string
+	ContentType string
+}
+
+// do sends a JSON request built from method, url and body, retrying transient
+// failures with exponential backoff. The caller must close the returned
+// response body.
+func (c *JiraClient) do(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	return c.doWith(ctx, method, url, body, contentHeaders{})
+}
+
+// doWith is do with the content negotiation headers replaced by headers.
+func (c *JiraClient) doWith(ctx context.Context, method, url string, body []byte, headers contentHeaders) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+
+		if err := waitForLimiter(ctx, c.Limiter); err != nil {
+			return nil, err
+		}
+		if err := c.waitForRateLimitReset(ctx); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, reader)
+		if err != nil {
+			return nil, err
+		}
+		setCommonHeaders(req, c.creds, c.Headers)
+		if c.AuthType == BearerAuth {
+			req.Header.Set("Authorization", "Bearer "+c.creds)
+		}
+		if headers.Accept != "" {
+			req.Header.Set("Accept", headers.Accept)
+		}
+		if headers.ContentType != "" {
+			req.Header.Set("Content-Type", headers.ContentType)
+		}
+
+		start := time.Now()
+		resp, err := c.httpClient.Do(req)
+		c.Metrics.observeRequest("jira", method, req.URL.Path, start)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
 		}
+		c.recordRateLimit(resp)
+
+		if !isRetryableStatus(resp.StatusCode) || attempt >= c.MaxRetries {
+			return resp, nil
+		}
+
+		delay, ok := retryAfter(resp)
+		if !ok {
+			delay = fullJitter(c.RetryBaseDelay<<attempt, c.Rand, &c.randMu)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// waitForLimiter blocks until limiter allows another request. A nil limiter
+// never blocks.
+func waitForLimiter(ctx context.Context, limiter *rate.Limiter) error {
+	if limiter == nil {
+		return nil
+	}
+
+	if err := limiter.Wait(ctx); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// fullJitter returns a random duration between 0 and max, so clients that
+// were throttled together don't retry in lockstep. mu guards rng, which isn't
+// safe for concurrent use; a nil rng uses the global source.
+func fullJitter(max time.Duration, rng *rand.Rand, mu *sync.Mutex) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	if rng == nil {
+		return rand.N(max + 1)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return time.Duration(rng.Int64N(int64(max) + 1))
+}
+
+// retryAfter parses the Retry-After header, which Jira sends either as a
+// number of seconds or as an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if when, err := http.ParseTime(value); err == nil {
+		delay := time.Until(when)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
 	}
+
+	return 0, false
 }
 
-func TestUpdateCustomField(t *testing.T) {
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusNoContent)
-	}))
+// fetchIssues is a wrapper around JiraClient.FetchIssues for callers that
+// don't keep a client around.
+func fetchIssues(ctx context.Context, encodedCredentials, baseURL, jql string, opts ...FetchOptions) (FetchResult, error) {
+	client, err := NewJiraClient(baseURL, encodedCredentials, nil)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	return client.FetchIssues(ctx, jql, opts...)
+}
 
-	defer ts.Close()
+// updateCustomField is a wrapper around JiraClient.UpdateCustomField for
+// callers that don't keep a client around.
+func updateCustomField(ctx context.Context, issueKey, fieldID string, value interface{}, encodedCredentials, baseURL string) (int, string, error) {
+	client, err := NewJiraClient(baseURL, encodedCredentials, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	return client.UpdateCustomField(ctx, issueKey, fieldID, value)
+}
 
-	statusCode, _, err := updateCustomField("TU-1", "customfield_10506", map[string]interface{}{"id": "11755"}, "encodedCredentials", ts.URL)
+// updateFields is a wrapper around JiraClient.UpdateFields for callers that
+// don't keep a client around.
+func updateFields(ctx context.Context, issueKey string, fields map[string]interface{}, encodedCredentials, baseURL string) (int, string, error) {
+	client, err := NewJiraClient(baseURL, encodedCredentials, nil)
 	if err != nil {
-		t.Fatalf("Error updating custom field: %v", err)
+		return 0, "", err
 	}
+	return client.UpdateFields(ctx, issueKey, fields)
+}
 
-	if statusCode != http.StatusNoContent {
-		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, statusCode)
+// fetchIssuesByKeys is a wrapper around JiraClient.FetchIssuesByKeys for
+// callers that don't keep a client around.
+func fetchIssuesByKeys(ctx context.Context, keys []string, encodedCredentials, baseURL string) ([]Issue, error) {
+	client, err := NewJiraClient(baseURL, encodedCredentials, nil)
+	if err != nil {
+		return nil, err
 	}
+	return client.FetchIssuesByKeys(ctx, keys)
 }
 
-func TestMain(m *testing.M) {
-	os.Setenv("CI", "false")
+// fetchIssue is a wrapper around JiraClient.FetchIssue for callers that don't
+// keep a client around.
+func fetchIssue(ctx context.Context, issueKey, encodedCredentials, baseURL string) (Issue, error) {
+	client, err := NewJiraClient(baseURL, encodedCredentials, nil)
+	if err != nil {
+		return Issue{}, err
+	}
+	return client.FetchIssue(ctx, issueKey)
+}
 
-	exitVal := m.Run()
+// FetchResult is everything FetchIssues learned from a search.
+type FetchResult struct {
+	Issues []Issue
+	// Total is the number of matching issues Jira reported. The v3 endpoint
+	// doesn't report one, so there it is the number of issues collected.
+	Total int
+	// StatusCode is the status of the last response received, which is the
+	// failing one when an error is returned.
+	StatusCode int
+	// Cursor is where the search continues when Limit stopped it early: the
+	// startAt offset on v2, the nextPageToken on v3. It is "" once every
+	// issue has been fetched.
+	Cursor string
+}
 
-	os.Unsetenv("CI")
+// FetchIssues returns every issue matching jql. An empty jql falls back to
+// defaultJQL. Cancelling ctx aborts the in-flight request and returns
+// ctx.Err().
+//
+// With APIVersion2 it follows startAt/maxResults/total on /rest/api/2/search;
+// with APIVersion3 it follows nextPageToken/isLast on /rest/api/3/search/jql,
+// where options.StartAt is ignored. Either way options.Limit, if set, caps
+// how many issues are collected.
+//
+// Issues come back in the order of jql's ORDER BY, if any, across pages.
+// Offset paging re-runs the query for every page, so an ORDER BY on fields
+// that tie, such as priority, could shuffle issues across page boundaries;
+// stableOrderJQL adds key as a final tiebreaker, and an issue seen on an
+// earlier page is not returned twice.
+func (c *JiraClient) FetchIssues(ctx context.Context, jql string, opts ...FetchOptions) (FetchResult, error) {
+	if jql == "" {
+		jql = defaultJQL
+	}
 
-	os.Exit(exitVal)
+	options := FetchOptions{MaxResults: defaultPageSize}
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	if options.MaxResults <= 0 {
+		options.MaxResults = defaultPageSize
+	}
+	if len(options.Fields) == 0 {
+		options.Fields = defaultFields
+	}
+
+	var result FetchResult
+	var err error
+	if c.APIVersion == APIVersion3 {
+		result, err = c.fetchIssuesByToken(ctx, jql, options)
+	} else {
+		result, err = c.fetchIssuesByOffset(ctx, jql, options)
+	}
+	c.Metrics.observeJiraFetch(result.StatusCode, err)
+	if err != nil {
+		return FetchResult{StatusCode: result.StatusCode}, err
+	}
+
+	c.log().Info("fetched issues", "jql", jql, "count", len(result.Issues), "total", result.Total)
+	return result, nil
 }
 
-func TestFetchIssuesWithMockJira(t *testing.T) {
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		response := IssueResponse{Issues: []Issue{{Key: "JIRA-1"}, {Key: "JIRA-2"}}}
-		err := json.NewEncoder(w).Encode(response)
+func (c *JiraClient) fetchIssuesByOffset(ctx context.Context, jql string, options FetchOptions) (FetchResult, error) {
+	if options.Cursor != "" {
+		startAt, err := strconv.Atoi(options.Cursor)
+		if err != nil || startAt < 0 {
+			return FetchResult{}, fmt.Errorf("invalid search cursor %q", options.Cursor)
+		}
+		options.StartAt = startAt
+	}
+
+	jql = stableOrderJQL(jql)
+	result := FetchResult{}
+	startAt := options.StartAt
+	// Duplicates are dropped as pages come in, so Limit counts distinct
+	// issues and the cursor points just past the last one kept.
+	seen := map[string]bool{}
+	collected := 0
+	issues, err := collectAll(func(cursor string) ([]Issue, string, error) {
+		pageSize := options.limitPageSize(collected)
+		page, status, err := c.fetchIssuePage(ctx, searchURLWith(buildSearchURL(c.baseURL, jql, startAt, pageSize), options))
+		result.StatusCode = status
 		if err != nil {
-			t.Fatalf("Error encoding response: %v", err)
+			c.log().Error("issue search failed", "jql", jql, "startAt", startAt, "statusCode", status, "error", err)
+			return nil, "", err
+		}
+
+		result.Total = page.Total
+		fresh := []Issue{}
+		for _, issue := range page.Issues {
+			if options.limitReached(collected) {
+				break
+			}
+			startAt++
+			if !seen[issue.Key] {
+				seen[issue.Key] = true
+				fresh = append(fresh, issue)
+				collected++
+			}
+		}
+
+		// Jira can report a total that includes issues the user isn't
+		// allowed to see, then serve an empty page where they would be. Stop
+		// there rather than asking for the same page forever.
+		if len(page.Issues) == 0 || startAt >= page.Total {
+			return fresh, "", nil
+		}
+		if options.limitReached(collected) {
+			result.Cursor = strconv.Itoa(startAt)
+			return fresh, "", nil
 		}
-	}))
+		return fresh, strconv.Itoa(startAt), nil
+	})
+	if err != nil {
+		return result, err
+	}
+	result.Issues = issues
+
+	if startAt < result.Total && options.Limit == 0 {
+		c.log().Warn("issue search returned fewer issues than its total", "jql", jql, "total", result.Total, "collected", len(result.Issues))
+	}
 
-	defer ts.Close()
+	return result, nil
+}
 
-	issues, _, err := fetchIssues("encodedCredentials", ts.URL)
+func (c *JiraClient) fetchIssuesByToken(ctx context.Context, jql string, options FetchOptions) (FetchResult, error) {
+	result := FetchResult{}
+	collected := 0
+	issues, err := collectAll(func(token string) ([]Issue, string, error) {
+		if collected == 0 && token == "" {
+			token = options.Cursor
+		}
+		page, status, err := c.fetchIssuePage(ctx, searchURLWith(buildSearchJQLURL(c.baseURL, jql, token, options.limitPageSize(collected)), options))
+		result.StatusCode = status
+		if err != nil {
+			c.log().Error("issue search failed", "jql", jql, "nextPageToken", token, "statusCode", status, "error", err)
+			return nil, "", err
+		}
+
+		collected += len(page.Issues)
+		if page.IsLast {
+			return page.Issues, "", nil
+		}
+		if options.limitReached(collected) {
+			result.Cursor = page.NextPageToken
+			return page.Issues, "", nil
+		}
+		return page.Issues, page.NextPageToken, nil
+	})
 	if err != nil {
-		t.Fatalf("Error fetching issues: %v", err)
+		return result, err
+	}
+
+	result.Issues = options.trim(issues)
+	result.Total = len(result.Issues)
+	return result, nil
+}
+
+// limitPageSize is MaxResults, reduced so the page doesn't go past Limit
+// once collected issues are in hand.
+func (o FetchOptions) limitPageSize(collected int) int {
+	if o.Limit > 0 {
+		return max(1, min(o.MaxResults, o.Limit-collected))
+	}
+	return o.MaxResults
+}
+
+func (o FetchOptions) limitReached(collected int) bool {
+	return o.Limit > 0 && collected >= o.Limit
+}
+
+// trim drops issues past Limit, for servers that send more than asked.
+func (o FetchOptions) trim(issues []Issue) []Issue {
+	if o.Limit > 0 && len(issues) > o.Limit {
+		return issues[:o.Limit]
 	}
+	return issues
+}
 
-	expectedIssues := []Issue{{Key: "JIRA-1"}, {Key: "JIRA-2"}}
-	if len(issues) != len(expectedIssues) {
-		t.Errorf("Expected %d issues, got %d", len(expectedIssues), len(issues))
+// stableOrderJQL appends key to jql's ORDER BY unless it already orders by
+// key or id, so issues that tie on the other sort fields keep the same order
+// on every page. jql without an ORDER BY is returned unchanged.
+func stableOrderJQL(jql string) string {
+	_, fields, ok := orderByIndex(jql)
+	if !ok || ordersByKey(jql[fields:]) {
+		return jql
 	}
+	return strings.TrimRight(jql, " ") + ", key ASC"
+}
 
-	for i, issue := range issues {
-		if issue.Key != expectedIssues[i].Key {
-			t.Errorf("Expected issue %d key to be %s, got %s", i, expectedIssues[i].Key, issue.Key)
+// ordersByKey reports whether an ORDER BY field list, such as
+// "rank, key DESC", sorts by key, issuekey or id.
+func ordersByKey(fields string) bool {
+	for _, field := range strings.Split(fields, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(field), " ")
+		switch strings.ToLower(strings.Trim(name, `"'`)) {
+		case "key", "issuekey", "id":
+			return true
 		}
 	}
+	return false
+}
+
+// joinURL appends apiPath, which may carry a query, to baseURL. Any context
+// path on baseURL, such as the /jira of a self-hosted instance, is kept, and
+// a trailing slash on it doesn't produce a double slash.
+func joinURL(baseURL, apiPath string) string {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return strings.TrimSuffix(baseURL, "/") + apiPath
+	}
+	ref, err := url.Parse(strings.TrimPrefix(apiPath, "/"))
+	if err != nil {
+		return strings.TrimSuffix(baseURL, "/") + apiPath
+	}
+
+	// Resolving against a path without a trailing slash would replace its
+	// last segment rather than append to it.
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+		base.RawPath = ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// buildSearchURL returns the /rest/api/2/search URL for one page of results,
+// with jql escaped as a query parameter.
+func buildSearchURL(baseURL, jql string, startAt, maxResults int) string {
+	query := url.Values{}
+	query.Set("jql", jql)
+	query.Set("startAt", strconv.Itoa(startAt))
+	query.Set("maxResults", strconv.Itoa(maxResults))
+
+	return joinURL(baseURL, "/rest/api/2/search?"+query.Encode())
+}
+
+// buildSearchJQLURL returns the /rest/api/3/search/jql URL for the page
+// identified by nextPageToken, or the first page when it is empty. Unlike v2,
+// this endpoint only returns issue ids unless fields are requested, which
+// searchURLWith does.
+func buildSearchJQLURL(baseURL, jql, nextPageToken string, maxResults int) string {
+	query := url.Values{}
+	query.Set("jql", jql)
+	query.Set("maxResults", strconv.Itoa(maxResults))
+	if nextPageToken != "" {
+		query.Set("nextPageToken", nextPageToken)
+	}
+
+	return joinURL(baseURL, "/rest/api/3/search/jql?"+query.Encode())
+}
+
+// withExpand adds expand to a search URL built by buildSearchURL or
+// buildSearchJQLURL. An empty expand leaves the URL unchanged.
+func withExpand(searchURL string, expand []string) string {
+	if len(expand) == 0 {
+		return searchURL
+	}
+	return searchURL + "&expand=" + url.QueryEscape(strings.Join(expand, ","))
+}
+
+// withFields is withExpand for the fields parameter.
+func withFields(searchURL string, fields []string) string {
+	if len(fields) == 0 {
+		return searchURL
+	}
+	return searchURL + "&fields=" + url.QueryEscape(strings.Join(fields, ","))
+}
+
+// searchURLWith adds the expand and fields of options to a search URL.
+func searchURLWith(searchURL string, options FetchOptions) string {
+	return withFields(withExpand(searchURL, options.Expand), options.Fields)
+}
+
+func (c *JiraClient) fetchIssuePage(ctx context.Context, searchURL string) (*IssueResponse, int, error) {
+	resp, err := c.do(ctx, "GET", searchURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, httpError(resp.StatusCode, readErrorBody(resp))
+	}
+
+	var page IssueResponse
+	if err := c.decode(resp, &page); err != nil {
+		return nil, resp.StatusCode, err
+	}
+
+	return &page, resp.StatusCode, nil
 }
 
-func TestFetchIssuesErrorHandling(t *testing.T) {
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusNotFound)
-		_, err := w.Write([]byte{})
+// maxKeysPerSearch keeps each key IN (...) clause well under Jira's JQL
+// length limit.
+const maxKeysPerSearch = 100
+
+// FetchIssuesByKeys returns the issues with the given keys, searching in
+// chunks of maxKeysPerSearch. Issues come back in the order of keys; keys
+// Jira doesn't return, such as deleted issues, are left out. fields is
+// passed on as FetchOptions.Fields.
+func (c *JiraClient) FetchIssuesByKeys(ctx context.Context, keys []string, fields ...string) ([]Issue, error) {
+	for _, key := range keys {
+		if err := validateIssueKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	found := map[string]Issue{}
+	for start := 0; start < len(keys); start += maxKeysPerSearch {
+		end := min(start+maxKeysPerSearch, len(keys))
+		jql := "key IN (" + strings.Join(keys[start:end], ", ") + ")"
+
+		result, err := c.FetchIssues(ctx, jql, FetchOptions{MaxResults: maxKeysPerSearch, Fields: fields})
 		if err != nil {
-			t.Fatalf("Error writing response: %v", err)
+			return nil, err
+		}
+		for _, issue := range result.Issues {
+			found[issue.Key] = issue
 		}
-	}))
+	}
+
+	issues := []Issue{}
+	for _, key := range keys {
+		if issue, ok := found[key]; ok {
+			issues = append(issues, issue)
+			delete(found, key)
+		}
+	}
+	return issues, nil
+}
 
-	defer ts.Close()
+// FetchIssue returns the issue issueKey, read directly rather than through a
+// search. fields limits what Jira sends, as FetchOptions.Fields does; none
+// sends every field. An issue that doesn't exist, or that the user can't
+// see, gives an error wrapping ErrNotFound.
+func (c *JiraClient) FetchIssue(ctx context.Context, issueKey string, fields ...string) (Issue, error) {
+	issueURL, err := c.issueURL(issueKey)
+	if err != nil {
+		return Issue{}, err
+	}
+	if len(fields) > 0 {
+		issueURL += "?fields=" + url.QueryEscape(strings.Join(fields, ","))
+	}
 
-	baseURL = ts.URL
+	resp, err := c.do(ctx, "GET", issueURL, nil)
+	if err != nil {
+		return Issue{}, err
+	}
+	defer resp.Body.Close()
 
-	_, _, err := fetchIssues("encodedCredentials", baseURL)
-	if err == nil {
-		t.Fatal("Expected an error, got nil")
+	if resp.StatusCode != http.StatusOK {
+		err := httpError(resp.StatusCode, readErrorBody(resp))
+		if errors.Is(err, ErrNotFound) {
+			return Issue{}, fmt.Errorf("issue %s not found: %w", issueKey, err)
+		}
+		return Issue{}, err
 	}
 
-	expectedError := "HTTP error! Status: 404, Body: "
-	if got := err.Error(); got != expectedError {
-		t.Errorf("Expected %q, got %q", expectedError, got)
+	var issue Issue
+	if err := c.decode(resp, &issue); err != nil {
+		return Issue{}, err
 	}
+	return issue, nil
 }
 
-func TestSetCommonHeaders(t *testing.T) {
-	req, _ := http.NewRequest("GET", "http://example.com", nil)
-	encodedCredentials := "encodedCredentials"
-	setCommonHeaders(req, encodedCredentials)
+// UpdateCustomField sets a single field on an issue. It returns the response
+// status code and body alongside any error.
+func (c *JiraClient) UpdateCustomField(ctx context.Context, issueKey, fieldID string, value interface{}) (int, string, error) {
+	statusCode, body, err := c.updateFields(ctx, issueKey, map[string]interface{}{fieldID: value})
+	c.Metrics.observeJiraUpdate(statusCode, err)
+	if err != nil {
+		c.log().Error("custom field update failed", "issueKey", issueKey, "fieldID", fieldID, "statusCode", statusCode, "error", err)
+		return statusCode, body, err
+	}
+
+	c.log().Info("updated custom field", "issueKey", issueKey, "fieldID", fieldID, "statusCode", statusCode)
+	return statusCode, body, nil
+}
 
-	if req.Header.Get("Authorization") != "Basic "+encodedCredentials {
-		t.Errorf("Expected Authorization header to be %s, got %s", "Basic "+encodedCredentials, req.Header.Get("Authorization"))
+// UpdateFields sets several fields on an issue in a single request, keyed by
+// field id. It returns the response status code and body alongside any
+// error.
+func (c *JiraClient) UpdateFields(ctx context.Context, issueKey string, fields map[string]interface{}) (int, string, error) {
+	fieldIDs := make([]string, 0, len(fields))
+	for fieldID := range fields {
+		fieldIDs = append(fieldIDs, fieldID)
 	}
+	sort.Strings(fieldIDs)
 
-	if req.Header.Get("Accept") != "application/json" {
-		t.Errorf("Expected Accept header to be %s, got %s", "application/json", req.Header.Get("Accept"))
+	statusCode, body, err := c.updateFields(ctx, issueKey, fields)
+	c.Metrics.observeJiraUpdate(statusCode, err)
+	if err != nil {
+		c.log().Error("field update failed", "issueKey", issueKey, "fieldIDs", fieldIDs, "statusCode", statusCode, "error", err)
+		return statusCode, body, err
 	}
 
-	if req.Header.Get("Content-Type") != "application/json" {
-		t.Errorf("Expected Content-Type header to be %s, got %s", "application/json", req.Header.Get("Content-Type"))
+	c.log().Info("updated fields", "issueKey", issueKey, "fieldIDs", fieldIDs, "statusCode", statusCode)
+	return statusCode, body, nil
+}
+
+func (c *JiraClient) updateFields(ctx context.Context, issueKey string, fields map[string]interface{}) (int, string, error) {
+	payload, err := json.Marshal(map[string]interface{}{"fields": fields})
+	if err != nil {
+		return 0, "", err
 	}
+
+	issueURL, err := c.issueURL(issueKey)
+	if err != nil {
+		return 0, "", err
+	}
+	if c.DryRun {
+		logDryRun(c.log(), "PUT", issueURL, payload)
+		return http.StatusNoContent, "", nil
+	}
+
+	resp, err := c.do(ctx, "PUT", issueURL, payload)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body := readErrorBody(resp)
+		return resp.StatusCode, body, httpError(resp.StatusCode, body)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, "", err
+	}
+
+	return resp.StatusCode, string(body), nil
 }
 
-func TestUpdateCustomFieldErrorHandling(t *testing.T) {
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusInternalServerError)
-		_, err := w.Write([]byte{})
+// projectKeyExpr matches a Jira project key such as TU, A or OPS_2: an
+// uppercase letter, then uppercase letters, digits or underscores.
+const projectKeyExpr = `[A-Z][A-Z0-9_]*`
+
+// issueKeyPattern matches keys like TU-123: a project key, a hyphen, and the
+// issue number.
+var issueKeyPattern = regexp.MustCompile(`^` + projectKeyExpr + `-[0-9]+$`)
+
+// validateIssueKey rejects keys that don't look like Jira issue keys, so bad
+// data is reported before a request is built from it.
+func validateIssueKey(issueKey string) error {
+	if !issueKeyPattern.MatchString(issueKey) {
+		return fmt.Errorf("invalid issue key %q", issueKey)
+	}
+	return nil
+}
+
+// issueURL returns the /rest/api/2/issue URL for issueKey, which is validated
+// and escaped as a path segment.
+func (c *JiraClient) issueURL(issueKey string) (string, error) {
+	if err := validateIssueKey(issueKey); err != nil {
+		return "", err
+	}
+	return joinURL(c.baseURL, "/rest/api/2/issue/"+url.PathEscape(issueKey)), nil
+}
+
+// loadJiraCredentials builds the Basic auth credentials from JIRA_EMAIL and
+// JIRA_API_TOKEN.
+func loadJiraCredentials() (string, error) {
+	email, err := requireEnv("JIRA_EMAIL")
+	if err != nil {
+		return "", err
+	}
+
+	token, err := requireEnv("JIRA_API_TOKEN")
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString([]byte(email + ":" + token)), nil
+}
+
+// loadNotionToken reads the Notion integration token from NOTION_TOKEN.
+func loadNotionToken() (string, error) {
+	return requireEnv("NOTION_TOKEN")
+}
+
+func requireEnv(name string) (string, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return "", fmt.Errorf("missing required environment variable %s", name)
+	}
+	return value, nil
+}
+
+// envDuration parses the environment variable name as a time.Duration, such
+// as 90s or 5m. It is 0 when the variable is unset.
+func envDuration(name string) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	return d, nil
+}
+
+func main() {
+	since := flag.String("since", "", "only sync issues updated in this window, e.g. 24h or 7d, or since an RFC 3339 time; overrides SYNC_WATERMARK_FILE")
+	listen := flag.String("listen", "", "serve Jira webhooks at /webhook on this address, e.g. :8080, instead of running a batch sync")
+	projects := flag.String("projects", "", "only sync these comma-separated Jira projects, e.g. ENG,OPS, instead of the configured JQL")
+	reconcile := flag.Bool("reconcile", false, "report which Notion pages differ from Jira, without writing, and exit")
+	validate := flag.Bool("validate", false, "check the field mapping against the live Jira and Notion schemas and exit")
+	configPath := flag.String("config", "", "read the JQL, database id, field mapping and rate limits from this YAML or JSON file")
+	debug := flag.Bool("debug", false, "log every HTTP request and response, with credentials redacted")
+	verifyWrites := flag.Bool("verify-writes", false, "read each Notion page back after writing it and warn about properties stored differently")
+	flag.Parse()
+	if *debug {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+	}
+
+	var cfg Config
+	var err error
+	mapping := defaultFieldMapping
+	if *configPath != "" {
+		cfg, err = LoadConfig(*configPath)
 		if err != nil {
-			t.Fatalf("Error writing response: %v", err)
+			log.Fatal(err)
 		}
-	}))
+		mapping = cfg.Mapping
+		if len(cfg.NotionUsers) > 0 {
+			NotionUserIDs = cfg.NotionUsers
+		}
+	}
 
-	defer ts.Close()
+	var sinceTime time.Time
+	if *since != "" {
+		sinceTime, err = parseSince(*since, time.Now())
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// SIGINT or SIGTERM stops the run between issues; see SyncRoutes.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	statusCode, _, err := updateCustomField("TU-1", "customfield_10506", map[string]interface{}{"id": "11755"}, "encodedCredentials", ts.URL)
-	if err == nil {
-		t.Fatal("Expected an error, got nil")
+	authType := BasicAuth
+	creds := os.Getenv("JIRA_PAT")
+	if creds != "" {
+		authType = BearerAuth
+	} else {
+		creds, err = loadJiraCredentials()
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	if statusCode != http.StatusInternalServerError {
-		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, statusCode)
+	var httpClient *http.Client
+	tlsOptions := TLSOptions{CAFile: os.Getenv("JIRA_CA_BUNDLE"), InsecureSkipVerify: os.Getenv("JIRA_INSECURE_SKIP_VERIFY") == "true"}
+	if tlsOptions.CAFile != "" || tlsOptions.InsecureSkipVerify {
+		httpClient, err = NewTLSHTTPClient(tlsOptions, slog.Default())
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *debug {
+		httpClient = withDebugLogging(httpClient, slog.Default())
+	}
+	jira, err := NewJiraClient(os.Getenv("JIRA_BASE_URL"), creds, httpClient)
+	if err != nil {
+		log.Fatal(err)
+	}
+	jira.AuthType = authType
+	jira.Logger = slog.Default()
+	if version := os.Getenv("JIRA_API_VERSION"); version != "" {
+		jira.APIVersion = version
 	}
+	if cfg.RateLimits.Jira > 0 {
+		jira.Limiter = rate.NewLimiter(rate.Limit(cfg.RateLimits.Jira), 1)
+	}
+
+	notionToken, err := loadNotionToken()
+	if err != nil {
+		log.Fatal(err)
+	}
+	databaseID := cfg.DatabaseID
+	if databaseID == "" {
+		databaseID, err = requireEnv("NOTION_DATABASE_ID")
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	var notionHTTPClient *http.Client
+	if *debug {
+		notionHTTPClient = withDebugLogging(nil, slog.Default())
+	}
+	notion := NewNotionClient(notionToken, notionHTTPClient)
+	notion.Logger = slog.Default()
+	notion.VerifyWrites = *verifyWrites
+	if version := os.Getenv("NOTION_VERSION"); version != "" {
+		notion.APIVersion = version
+	}
+	if cfg.RateLimits.Notion > 0 {
+		notion.Limiter = rate.NewLimiter(rate.Limit(cfg.RateLimits.Notion), 1)
+	}
+	if name := os.Getenv("SYNC_JIRA_KEY_PROPERTY"); name != "" {
+		notion.JiraKeyProperty = name
+	}
+	notion.JiraInstance = os.Getenv("SYNC_JIRA_INSTANCE")
+	notion.JiraIDProperty = os.Getenv("SYNC_JIRA_ID_PROPERTY")
+	metadata := PageMetadata{
+		JiraWebBaseURL:     os.Getenv("JIRA_WEB_BASE_URL"),
+		JiraURLProperty:    os.Getenv("SYNC_JIRA_URL_PROPERTY"),
+		LastSyncedProperty: defaultLastSyncedProperty,
+	}
+	// An empty SYNC_LAST_SYNCED_PROPERTY turns the timestamp off.
+	if name, ok := os.LookupEnv("SYNC_LAST_SYNCED_PROPERTY"); ok {
+		metadata.LastSyncedProperty = name
+	}
+
+	if _, err := jira.Ping(ctx); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := notion.Ping(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	if *validate {
+		problems, err := ValidateMapping(ctx, mapping, jira, notion, databaseID, metadata)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, problem := range problems {
+			log.Print(problem)
+		}
+		if len(problems) > 0 {
+			log.Fatalf("field mapping has %d problems", len(problems))
+		}
+		log.Print("field mapping is valid")
+		return
+	}
+
+	if err := CheckJiraKeyProperty(ctx, notion, databaseID); err != nil {
+		log.Fatal(err)
+	}
+
+	if *listen != "" {
+		secret, err := requireEnv("JIRA_WEBHOOK_SECRET")
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	expectedError := "HTTP error! Status: 500, Body: "
-	if got := err.Error(); got != expectedError {
-		t.Errorf("Expected %q, got %q", expectedError, got)
+		mux := http.NewServeMux()
+		mux.Handle("/webhook", &WebhookHandler{
+			Secret: secret,
+			Logger: slog.Default(),
+			Sync: func(ctx context.Context, issueKey string) error {
+				return SyncIssue(ctx, jira, notion, mapping, databaseID, issueKey, SyncOptions{Metadata: metadata})
+			},
+		})
+		server := &http.Server{Addr: *listen, Handler: mux}
+		drained := make(chan struct{})
+		go func() {
+			defer close(drained)
+			<-ctx.Done()
+			// Let webhook syncs already under way finish.
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+			defer cancel()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				log.Printf("Error shutting down: %v", err)
+			}
+		}()
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+		<-drained
+		return
+	}
+
+	options := SyncOptions{JQL: cfg.JQL, Metadata: metadata}
+	if options.JQL == "" {
+		options.JQL = os.Getenv("JIRA_JQL")
+	}
+	if *projects != "" {
+		options.JQL, err = projectsJQL(*projects)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if filterID := os.Getenv("JIRA_FILTER_ID"); filterID != "" && options.JQL == "" {
+		options.JQL, err = jira.FilterJQL(ctx, filterID)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *reconcile {
+		drifts, err := Reconcile(ctx, jira, notion, mapping, databaseID, options.JQL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, drift := range drifts {
+			if drift.PageID == "" {
+				log.Printf("%s: no Notion page", drift.Key)
+			}
+			for _, diff := range drift.Differences {
+				log.Printf("%s: %s", drift.Key, diff)
+			}
+		}
+		if len(drifts) > 0 {
+			log.Fatalf("%d issues differ from Notion", len(drifts))
+		}
+		log.Print("Notion matches Jira")
+		return
+	}
+
+	options.Stale, err = ParseStaleAction(os.Getenv("SYNC_STALE_ACTION"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// An explicit --since window is a one-off, so it neither reads nor
+	// advances the stored watermark.
+	watermarkPath := os.Getenv("SYNC_WATERMARK_FILE")
+	if !sinceTime.IsZero() {
+		options.LastSynced = sinceTime
+		watermarkPath = ""
+	} else if watermarkPath != "" {
+		options.LastSynced, err = loadWatermark(watermarkPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if statePath := os.Getenv("SYNC_STATE_FILE"); statePath != "" {
+		options.State, err = LoadSyncState(statePath, jira.Logger)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if value := os.Getenv("SYNC_MAX_ARCHIVE"); value != "" {
+		options.MaxArchive, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("invalid SYNC_MAX_ARCHIVE %q: %v", value, err)
+		}
+	}
+	if value := os.Getenv("SYNC_BATCH_SIZE"); value != "" {
+		options.BatchSize, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("invalid SYNC_BATCH_SIZE %q: %v", value, err)
+		}
+	}
+	if value := os.Getenv("SYNC_PROGRESS_EVERY"); value != "" {
+		options.ProgressEvery, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("invalid SYNC_PROGRESS_EVERY %q: %v", value, err)
+		}
+		options.Progress = func(p Progress) {
+			slog.Info("sync progress", "processed", p.Processed, "total", p.Total, "failed", p.Failed)
+		}
+	}
+	if value := os.Getenv("SYNC_MAX_ARCHIVE_PERCENT"); value != "" {
+		options.MaxArchivePercent, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			log.Fatalf("invalid SYNC_MAX_ARCHIVE_PERCENT %q: %v", value, err)
+		}
+	}
+
+	options.IssueTimeout, err = envDuration("SYNC_ISSUE_TIMEOUT")
+	if err != nil {
+		log.Fatal(err)
+	}
+	runTimeout, err := envDuration("SYNC_TIMEOUT")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if runTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runTimeout)
+		defer cancel()
+	}
+
+	report, err := SyncJiraToNotion(ctx, jira, notion, mapping, databaseID, options)
+	if err != nil {
+		log.Fatalf("Error syncing issues: %v", err)
+	}
+	log.Printf("Sync finished: %s", report)
+
+	if len(report.Failed) > 0 {
+		for _, failed := range report.Failed {
+			log.Printf("Failed to sync %s: %s", failed.Key, failed.Error)
+		}
+		log.Fatalf("Failed to sync %d issues", len(report.Failed))
+	}
+
+	if watermarkPath != "" {
+		if err := saveWatermark(watermarkPath, report.Watermark); err != nil {
+			log.Fatal(err)
+		}
 	}
 }
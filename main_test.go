@@ -1,11 +1,27 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
 )
 
 var baseURL string
@@ -23,17 +39,17 @@ func TestFetchIssues(t *testing.T) {
 
 	baseURL = ts.URL
 
-	issues, _, err := fetchIssues("encodedCredentials", baseURL)
+	result, err := fetchIssues(context.Background(), "encodedCredentials", baseURL, "")
 	if err != nil {
 		t.Fatalf("Error fetching issues: %v", err)
 	}
 
 	expectedIssues := []Issue{{Key: "TU-1"}, {Key: "TU-2"}}
-	if len(issues) != len(expectedIssues) {
-		t.Errorf("Expected %d issues, got %d", len(expectedIssues), len(issues))
+	if len(result.Issues) != len(expectedIssues) {
+		t.Errorf("Expected %d issues, got %d", len(expectedIssues), len(result.Issues))
 	}
 
-	for i, issue := range issues {
+	for i, issue := range result.Issues {
 		if issue.Key != expectedIssues[i].Key {
 			t.Errorf("Expected issue %d key to be %s, got %s", i, expectedIssues[i].Key, issue.Key)
 		}
@@ -47,7 +63,7 @@ func TestUpdateCustomField(t *testing.T) {
 
 	defer ts.Close()
 
-	statusCode, _, err := updateCustomField("TU-1", "customfield_10506", map[string]interface{}{"id": "11755"}, "encodedCredentials", ts.URL)
+	statusCode, _, err := updateCustomField(context.Background(), "TU-1", "customfield_10506", map[string]interface{}{"id": "11755"}, "encodedCredentials", ts.URL)
 	if err != nil {
 		t.Fatalf("Error updating custom field: %v", err)
 	}
@@ -57,6 +73,16 @@ func TestUpdateCustomField(t *testing.T) {
 	}
 }
 
+func newTestJiraClient(t *testing.T, url string) *JiraClient {
+	t.Helper()
+
+	client, err := NewJiraClient(url, "encodedCredentials", nil)
+	if err != nil {
+		t.Fatalf("Error creating client: %v", err)
+	}
+	return client
+}
+
 func TestMain(m *testing.M) {
 	os.Setenv("CI", "false")
 
@@ -78,17 +104,17 @@ func TestFetchIssuesWithMockJira(t *testing.T) {
 
 	defer ts.Close()
 
-	issues, _, err := fetchIssues("encodedCredentials", ts.URL)
+	result, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, "")
 	if err != nil {
 		t.Fatalf("Error fetching issues: %v", err)
 	}
 
 	expectedIssues := []Issue{{Key: "JIRA-1"}, {Key: "JIRA-2"}}
-	if len(issues) != len(expectedIssues) {
-		t.Errorf("Expected %d issues, got %d", len(expectedIssues), len(issues))
+	if len(result.Issues) != len(expectedIssues) {
+		t.Errorf("Expected %d issues, got %d", len(expectedIssues), len(result.Issues))
 	}
 
-	for i, issue := range issues {
+	for i, issue := range result.Issues {
 		if issue.Key != expectedIssues[i].Key {
 			t.Errorf("Expected issue %d key to be %s, got %s", i, expectedIssues[i].Key, issue.Key)
 		}
@@ -98,7 +124,7 @@ func TestFetchIssuesWithMockJira(t *testing.T) {
 func TestFetchIssuesErrorHandling(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
-		_, err := w.Write([]byte{})
+		_, err := w.Write([]byte(`{"errorMessages":["Issue does not exist"]}`))
 		if err != nil {
 			t.Fatalf("Error writing response: %v", err)
 		}
@@ -108,12 +134,16 @@ func TestFetchIssuesErrorHandling(t *testing.T) {
 
 	baseURL = ts.URL
 
-	_, _, err := fetchIssues("encodedCredentials", baseURL)
+	result, err := fetchIssues(context.Background(), "encodedCredentials", baseURL, "")
 	if err == nil {
 		t.Fatal("Expected an error, got nil")
 	}
 
-	expectedError := "HTTP error! Status: 404, Body: "
+	if result.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, result.StatusCode)
+	}
+
+	expectedError := `HTTP error! Status: 404, Body: {"errorMessages":["Issue does not exist"]}`
 	if got := err.Error(); got != expectedError {
 		t.Errorf("Expected %q, got %q", expectedError, got)
 	}
@@ -122,7 +152,7 @@ func TestFetchIssuesErrorHandling(t *testing.T) {
 func TestSetCommonHeaders(t *testing.T) {
 	req, _ := http.NewRequest("GET", "http://example.com", nil)
 	encodedCredentials := "encodedCredentials"
-	setCommonHeaders(req, encodedCredentials)
+	setCommonHeaders(req, encodedCredentials, nil)
 
 	if req.Header.Get("Authorization") != "Basic "+encodedCredentials {
 		t.Errorf("Expected Authorization header to be %s, got %s", "Basic "+encodedCredentials, req.Header.Get("Authorization"))
@@ -140,7 +170,7 @@ func TestSetCommonHeaders(t *testing.T) {
 func TestUpdateCustomFieldErrorHandling(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
-		_, err := w.Write([]byte{})
+		_, err := w.Write([]byte(`{"errorMessages":["Internal server error"]}`))
 		if err != nil {
 			t.Fatalf("Error writing response: %v", err)
 		}
@@ -148,7 +178,7 @@ func TestUpdateCustomFieldErrorHandling(t *testing.T) {
 
 	defer ts.Close()
 
-	statusCode, _, err := updateCustomField("TU-1", "customfield_10506", map[string]interface{}{"id": "11755"}, "encodedCredentials", ts.URL)
+	statusCode, _, err := updateCustomField(context.Background(), "TU-1", "customfield_10506", map[string]interface{}{"id": "11755"}, "encodedCredentials", ts.URL)
 	if err == nil {
 		t.Fatal("Expected an error, got nil")
 	}
@@ -157,8 +187,1454 @@ func TestUpdateCustomFieldErrorHandling(t *testing.T) {
 		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, statusCode)
 	}
 
-	expectedError := "HTTP error! Status: 500, Body: "
+	expectedError := `HTTP error! Status: 500, Body: {"errorMessages":["Internal server error"]}`
 	if got := err.Error(); got != expectedError {
 		t.Errorf("Expected %q, got %q", expectedError, got)
 	}
 }
+
+func TestFetchIssuesPagination(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		if got := r.URL.Query().Get("maxResults"); got != "2" {
+			t.Errorf("Expected maxResults to be 2, got %s", got)
+		}
+
+		allIssues := []Issue{{Key: "TU-1"}, {Key: "TU-2"}, {Key: "TU-3"}, {Key: "TU-4"}, {Key: "TU-5"}}
+		end := startAt + 2
+		if end > len(allIssues) {
+			end = len(allIssues)
+		}
+
+		response := IssueResponse{StartAt: startAt, MaxResults: 2, Total: len(allIssues), Issues: allIssues[startAt:end]}
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+
+	defer ts.Close()
+
+	result, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, "", FetchOptions{MaxResults: 2})
+	if err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+
+	if len(result.Issues) != 5 {
+		t.Fatalf("Expected %d issues, got %d", 5, len(result.Issues))
+	}
+
+	for i, issue := range result.Issues {
+		expectedKey := "TU-" + strconv.Itoa(i+1)
+		if issue.Key != expectedKey {
+			t.Errorf("Expected issue %d key to be %s, got %s", i, expectedKey, issue.Key)
+		}
+	}
+
+	if requests != 3 {
+		t.Errorf("Expected %d requests, got %d", 3, requests)
+	}
+}
+
+func TestFetchIssuesResumeFromCursor(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		allIssues := []Issue{{Key: "TU-1"}, {Key: "TU-2"}, {Key: "TU-3"}, {Key: "TU-4"}}
+		end := startAt + 2
+		if end > len(allIssues) {
+			end = len(allIssues)
+		}
+
+		response := IssueResponse{StartAt: startAt, MaxResults: 2, Total: len(allIssues), Issues: allIssues[startAt:end]}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	first, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, "", FetchOptions{MaxResults: 2, Limit: 2})
+	if err != nil {
+		t.Fatalf("Error fetching page one: %v", err)
+	}
+	if len(first.Issues) != 2 || first.Issues[0].Key != "TU-1" || first.Cursor == "" {
+		t.Fatalf("Expected TU-1 and TU-2 with a cursor, got %+v", first)
+	}
+
+	state, err := LoadSyncState(filepath.Join(t.TempDir(), "state.json"), nil)
+	if err != nil {
+		t.Fatalf("Error loading state: %v", err)
+	}
+	if err := state.SetCursor("project = TU", first.Cursor); err != nil {
+		t.Fatalf("Error saving cursor: %v", err)
+	}
+
+	second, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, "", FetchOptions{MaxResults: 2, Limit: 2, Cursor: state.Cursor("project = TU")})
+	if err != nil {
+		t.Fatalf("Error fetching page two: %v", err)
+	}
+	if len(second.Issues) != 2 || second.Issues[0].Key != "TU-3" || second.Issues[1].Key != "TU-4" {
+		t.Errorf("Expected page two to hold TU-3 and TU-4, got %+v", second.Issues)
+	}
+	if second.Cursor != "" {
+		t.Errorf("Expected no cursor once the search is exhausted, got %q", second.Cursor)
+	}
+}
+
+func TestFetchIssuesMaxResponseSize(t *testing.T) {
+	summary := strings.Repeat("x", 4<<10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := IssueResponse{Total: 1, Issues: []Issue{{Key: "TU-1", Fields: IssueFields{Summary: summary}}}}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+	client.MaxResponseSize = 1 << 10
+	if _, err := client.FetchIssues(context.Background(), "project = TU"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Expected ErrResponseTooLarge, got %v", err)
+	}
+
+	client.MaxResponseSize = 64 << 10
+	result, err := client.FetchIssues(context.Background(), "project = TU")
+	if err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+	if len(result.Issues) != 1 || result.Issues[0].Fields.Summary != summary {
+		t.Errorf("Expected TU-1 with its full summary, got %d issues", len(result.Issues))
+	}
+}
+
+func TestFetchIssuesZeroTotal(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		response := IssueResponse{Total: 0, Issues: []Issue{}}
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+
+	defer ts.Close()
+
+	result, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, "")
+	if err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+
+	if result.Issues == nil || len(result.Issues) != 0 {
+		t.Errorf("Expected an empty slice, got %v", result.Issues)
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected %d request, got %d", 1, requests)
+	}
+}
+
+func TestFetchIssuesWithJQL(t *testing.T) {
+	jql := `project = "TU" AND updated >= -1d`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("jql"); got != jql {
+			t.Errorf("Expected jql to be %q, got %q", jql, got)
+		}
+
+		response := IssueResponse{Issues: []Issue{{Key: "TU-1"}}}
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+
+	defer ts.Close()
+
+	result, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, jql)
+	if err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+
+	if len(result.Issues) != 1 {
+		t.Errorf("Expected %d issues, got %d", 1, len(result.Issues))
+	}
+}
+
+func TestFetchIssuesDefaultJQL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("jql"); got != defaultJQL {
+			t.Errorf("Expected jql to be %q, got %q", defaultJQL, got)
+		}
+
+		err := json.NewEncoder(w).Encode(IssueResponse{})
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+
+	defer ts.Close()
+
+	_, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, "")
+	if err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+}
+
+func TestBuildSearchURL(t *testing.T) {
+	jql := `summary ~ "bet one" AND status = Done`
+	searchURL := buildSearchURL("http://example.com", jql, 50, 25)
+
+	parsed, err := url.Parse(searchURL)
+	if err != nil {
+		t.Fatalf("Error parsing URL: %v", err)
+	}
+
+	if parsed.Path != "/rest/api/2/search" {
+		t.Errorf("Expected path to be %s, got %s", "/rest/api/2/search", parsed.Path)
+	}
+
+	if strings.ContainsAny(parsed.RawQuery, ` "`) {
+		t.Errorf("Expected query to be escaped, got %s", parsed.RawQuery)
+	}
+
+	query := parsed.Query()
+	if got := query.Get("jql"); got != jql {
+		t.Errorf("Expected jql to be %q, got %q", jql, got)
+	}
+
+	if got := query.Get("startAt"); got != "50" {
+		t.Errorf("Expected startAt to be %s, got %s", "50", got)
+	}
+
+	if got := query.Get("maxResults"); got != "25" {
+		t.Errorf("Expected maxResults to be %s, got %s", "25", got)
+	}
+}
+
+func TestHTTPErrorBodyIsCapped(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, err := w.Write([]byte(strings.Repeat("x", maxErrorBodySize*2)))
+		if err != nil {
+			t.Fatalf("Error writing response: %v", err)
+		}
+	}))
+
+	defer ts.Close()
+
+	_, body, err := updateCustomField(context.Background(), "TU-1", "customfield_10506", map[string]interface{}{"id": "11755"}, "encodedCredentials", ts.URL)
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+
+	if len(body) != maxErrorBodySize {
+		t.Errorf("Expected body to be capped at %d bytes, got %d", maxErrorBodySize, len(body))
+	}
+
+	expectedError := "HTTP error! Status: 400, Body: " + strings.Repeat("x", maxErrorBodySize)
+	if got := err.Error(); got != expectedError {
+		t.Errorf("Expected error to contain the capped body, got %d bytes", len(got))
+	}
+}
+
+func TestFetchIssuesContextCancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := fetchIssues(ctx, "encodedCredentials", ts.URL, "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected fetchIssues to return promptly, took %v", elapsed)
+	}
+}
+
+func TestUpdateCustomFieldContextCancelled(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+
+	defer ts.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, _, err := updateCustomField(ctx, "TU-1", "customfield_10506", map[string]interface{}{"id": "11755"}, "encodedCredentials", ts.URL)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected %v, got %v", context.Canceled, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected updateCustomField to return promptly, took %v", elapsed)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestNewJiraClientDefaultTimeout(t *testing.T) {
+	client := newTestJiraClient(t, "http://example.com")
+
+	if client.httpClient.Timeout != defaultHTTPTimeout {
+		t.Errorf("Expected timeout to be %v, got %v", defaultHTTPTimeout, client.httpClient.Timeout)
+	}
+}
+
+func TestJiraClientUsesInjectedHTTPClient(t *testing.T) {
+	requests := 0
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		if req.URL.Host != "jira.example.com" {
+			t.Errorf("Expected host to be %s, got %s", "jira.example.com", req.URL.Host)
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusNoContent,
+			Body:       io.NopCloser(strings.NewReader("")),
+			Header:     make(http.Header),
+		}, nil
+	})}
+
+	client, err := NewJiraClient("https://jira.example.com", "encodedCredentials", httpClient)
+	if err != nil {
+		t.Fatalf("Error creating client: %v", err)
+	}
+
+	statusCode, _, err := client.UpdateCustomField(context.Background(), "TU-1", "customfield_10506", map[string]interface{}{"id": "11755"})
+	if err != nil {
+		t.Fatalf("Error updating custom field: %v", err)
+	}
+
+	if statusCode != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, statusCode)
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected %d request through the injected client, got %d", 1, requests)
+	}
+}
+
+func TestFetchIssuesRetriesTransientFailures(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		response := IssueResponse{Issues: []Issue{{Key: "TU-1"}}}
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+	client.RetryBaseDelay = 0
+
+	result, err := client.FetchIssues(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+
+	if result.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, result.StatusCode)
+	}
+
+	if len(result.Issues) != 1 {
+		t.Errorf("Expected %d issues, got %d", 1, len(result.Issues))
+	}
+
+	if requests != 3 {
+		t.Errorf("Expected %d requests, got %d", 3, requests)
+	}
+}
+
+func TestUpdateCustomFieldDoesNotRetryClientErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized} {
+		requests := 0
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(status)
+		}))
+
+		client := newTestJiraClient(t, ts.URL)
+		client.RetryBaseDelay = 0
+
+		_, _, err := client.UpdateCustomField(context.Background(), "TU-1", "customfield_10506", map[string]interface{}{"id": "11755"})
+		ts.Close()
+
+		if err == nil {
+			t.Fatalf("Expected an error for status %d, got nil", status)
+		}
+
+		if requests != 1 {
+			t.Errorf("Expected %d request for status %d, got %d", 1, status, requests)
+		}
+	}
+}
+
+func TestUpdateCustomFieldGivesUpAfterMaxRetries(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+	client.MaxRetries = 2
+	client.RetryBaseDelay = 0
+
+	statusCode, _, err := client.UpdateCustomField(context.Background(), "TU-1", "customfield_10506", map[string]interface{}{"id": "11755"})
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+
+	if statusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected status code %d, got %d", http.StatusTooManyRequests, statusCode)
+	}
+
+	if requests != 3 {
+		t.Errorf("Expected %d requests, got %d", 3, requests)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"7"}}}
+
+	delay, ok := retryAfter(resp)
+	if !ok {
+		t.Fatal("Expected Retry-After to be parsed")
+	}
+
+	if delay != 7*time.Second {
+		t.Errorf("Expected delay to be %v, got %v", 7*time.Second, delay)
+	}
+
+	if _, ok := retryAfter(&http.Response{Header: http.Header{}}); ok {
+		t.Error("Expected no delay without a Retry-After header")
+	}
+}
+
+func TestIssueUnmarshal(t *testing.T) {
+	data := []byte(`{
+		"id": "10042",
+		"key": "TU-42",
+		"fields": {
+			"summary": "Launch the onboarding bet",
+			"status": {"name": "In Progress", "id": "3"},
+			"assignee": {"accountId": "5b10a2844c20165700ede21g", "displayName": "Ada Lovelace"},
+			"priority": {"name": "High", "id": "2"},
+			"updated": "2024-03-01T12:34:56.000+0000"
+		}
+	}`)
+
+	var issue Issue
+	if err := json.Unmarshal(data, &issue); err != nil {
+		t.Fatalf("Error unmarshalling issue: %v", err)
+	}
+
+	if issue.Key != "TU-42" {
+		t.Errorf("Expected key to be %s, got %s", "TU-42", issue.Key)
+	}
+
+	if issue.Fields.Summary != "Launch the onboarding bet" {
+		t.Errorf("Expected summary to be %s, got %s", "Launch the onboarding bet", issue.Fields.Summary)
+	}
+
+	if issue.StatusName() != "In Progress" {
+		t.Errorf("Expected status to be %s, got %s", "In Progress", issue.StatusName())
+	}
+
+	if issue.AssigneeName() != "Ada Lovelace" {
+		t.Errorf("Expected assignee to be %s, got %s", "Ada Lovelace", issue.AssigneeName())
+	}
+
+	if issue.PriorityName() != "High" {
+		t.Errorf("Expected priority to be %s, got %s", "High", issue.PriorityName())
+	}
+
+	if issue.Fields.Updated != "2024-03-01T12:34:56.000+0000" {
+		t.Errorf("Expected updated to be %s, got %s", "2024-03-01T12:34:56.000+0000", issue.Fields.Updated)
+	}
+}
+
+func TestIssueUnmarshalNullAssignee(t *testing.T) {
+	data := []byte(`{"key": "TU-43", "fields": {"summary": "Unowned", "assignee": null, "priority": null}}`)
+
+	var issue Issue
+	if err := json.Unmarshal(data, &issue); err != nil {
+		t.Fatalf("Error unmarshalling issue: %v", err)
+	}
+
+	if issue.AssigneeName() != "" {
+		t.Errorf("Expected no assignee, got %s", issue.AssigneeName())
+	}
+
+	if issue.PriorityName() != "" {
+		t.Errorf("Expected no priority, got %s", issue.PriorityName())
+	}
+}
+
+func TestUpdateCustomFieldLogsStructuredAttributes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/TU-2") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	client := newTestJiraClient(t, ts.URL)
+	client.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+
+	_, _, _ = client.UpdateCustomField(context.Background(), "TU-1", "customfield_10506", map[string]interface{}{"id": "11755"})
+	_, _, _ = client.UpdateCustomField(context.Background(), "TU-2", "customfield_10506", map[string]interface{}{"id": "11755"})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected %d log lines, got %d: %s", 2, len(lines), buf.String())
+	}
+
+	expected := []struct {
+		level      string
+		issueKey   string
+		statusCode float64
+	}{
+		{"INFO", "TU-1", http.StatusNoContent},
+		{"ERROR", "TU-2", http.StatusInternalServerError},
+	}
+
+	for i, line := range lines {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Error decoding log line: %v", err)
+		}
+
+		if record["level"] != expected[i].level {
+			t.Errorf("Expected level %s, got %v", expected[i].level, record["level"])
+		}
+
+		if record["issueKey"] != expected[i].issueKey {
+			t.Errorf("Expected issueKey %s, got %v", expected[i].issueKey, record["issueKey"])
+		}
+
+		if record["fieldID"] != "customfield_10506" {
+			t.Errorf("Expected fieldID %s, got %v", "customfield_10506", record["fieldID"])
+		}
+
+		if record["statusCode"] != expected[i].statusCode {
+			t.Errorf("Expected statusCode %v, got %v", expected[i].statusCode, record["statusCode"])
+		}
+	}
+}
+
+func TestUpdateCustomFieldDryRun(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	client := newTestJiraClient(t, ts.URL)
+	client.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+	client.DryRun = true
+
+	statusCode, _, err := client.UpdateCustomField(context.Background(), "TU-1", "customfield_10506", map[string]interface{}{"id": "11755"})
+	if err != nil {
+		t.Fatalf("Error updating custom field: %v", err)
+	}
+
+	if statusCode != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, statusCode)
+	}
+
+	if requests != 0 {
+		t.Errorf("Expected no requests in dry run, got %d", requests)
+	}
+
+	if !strings.Contains(buf.String(), `{\"fields\":{\"customfield_10506\":{\"id\":\"11755\"}}}`) {
+		t.Errorf("Expected dry run log to contain the request body, got %s", buf.String())
+	}
+}
+
+func TestLoadJiraCredentials(t *testing.T) {
+	t.Setenv("JIRA_EMAIL", "ada@example.com")
+	t.Setenv("JIRA_API_TOKEN", "secret")
+
+	creds, err := loadJiraCredentials()
+	if err != nil {
+		t.Fatalf("Error loading credentials: %v", err)
+	}
+
+	expected := base64.StdEncoding.EncodeToString([]byte("ada@example.com:secret"))
+	if creds != expected {
+		t.Errorf("Expected credentials to be %s, got %s", expected, creds)
+	}
+}
+
+func TestLoadJiraCredentialsMissing(t *testing.T) {
+	tests := []struct {
+		email, token, missing string
+	}{
+		{"", "secret", "JIRA_EMAIL"},
+		{"ada@example.com", "", "JIRA_API_TOKEN"},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("JIRA_EMAIL", tt.email)
+		t.Setenv("JIRA_API_TOKEN", tt.token)
+
+		_, err := loadJiraCredentials()
+		if err == nil {
+			t.Fatalf("Expected an error when %s is missing, got nil", tt.missing)
+		}
+
+		expectedError := "missing required environment variable " + tt.missing
+		if got := err.Error(); got != expectedError {
+			t.Errorf("Expected %q, got %q", expectedError, got)
+		}
+	}
+}
+
+func TestLoadNotionToken(t *testing.T) {
+	t.Setenv("NOTION_TOKEN", "")
+	if _, err := loadNotionToken(); err == nil || !strings.Contains(err.Error(), "NOTION_TOKEN") {
+		t.Errorf("Expected an error naming NOTION_TOKEN, got %v", err)
+	}
+
+	t.Setenv("NOTION_TOKEN", "notionToken")
+	token, err := loadNotionToken()
+	if err != nil {
+		t.Fatalf("Error loading token: %v", err)
+	}
+
+	if token != "notionToken" {
+		t.Errorf("Expected token to be %s, got %s", "notionToken", token)
+	}
+}
+
+func TestFetchIssuesV3FollowsNextPageToken(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/rest/api/3/search/jql" {
+			t.Errorf("Expected path to be %s, got %s", "/rest/api/3/search/jql", r.URL.Path)
+		}
+
+		response := IssueResponse{Issues: []Issue{{Key: "TU-1"}, {Key: "TU-2"}}, NextPageToken: "page-2"}
+		if r.URL.Query().Get("nextPageToken") == "page-2" {
+			response = IssueResponse{Issues: []Issue{{Key: "TU-3"}}, IsLast: true}
+		}
+
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+	client.APIVersion = APIVersion3
+
+	result, err := client.FetchIssues(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+
+	expectedIssues := []Issue{{Key: "TU-1"}, {Key: "TU-2"}, {Key: "TU-3"}}
+	if len(result.Issues) != len(expectedIssues) {
+		t.Fatalf("Expected %d issues, got %d", len(expectedIssues), len(result.Issues))
+	}
+
+	for i, issue := range result.Issues {
+		if issue.Key != expectedIssues[i].Key {
+			t.Errorf("Expected issue %d key to be %s, got %s", i, expectedIssues[i].Key, issue.Key)
+		}
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected %d requests, got %d", 2, requests)
+	}
+}
+
+func TestFetchIssuesOrderedAcrossPages(t *testing.T) {
+	const jql = "project = TU ORDER BY priority DESC, updated DESC"
+	pages := map[string][]Issue{
+		"0": {{Key: "TU-3"}, {Key: "TU-1"}},
+		"2": {{Key: "TU-4"}, {Key: "TU-2"}},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("jql"); got != jql+", key ASC" {
+			t.Errorf("Expected jql to be %q, got %q", jql+", key ASC", got)
+		}
+
+		startAt := r.URL.Query().Get("startAt")
+		err := json.NewEncoder(w).Encode(IssueResponse{Total: 4, Issues: pages[startAt]})
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	result, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, jql, FetchOptions{MaxResults: 2})
+	if err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+
+	var keys []string
+	for _, issue := range result.Issues {
+		keys = append(keys, issue.Key)
+	}
+	if got := strings.Join(keys, ","); got != "TU-3,TU-1,TU-4,TU-2" {
+		t.Errorf("Expected issues in query order, got %s", got)
+	}
+}
+
+func TestFetchIssuesLimit(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+
+		// Pages are always 50 long, whatever maxResults asks for.
+		issues := make([]Issue, 50)
+		for i := range issues {
+			issues[i] = Issue{Key: fmt.Sprintf("TU-%d", startAt+i+1)}
+		}
+		if err := json.NewEncoder(w).Encode(IssueResponse{Total: 200, Issues: issues}); err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	result, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, "", FetchOptions{MaxResults: 50, Limit: 75})
+	if err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+
+	if len(result.Issues) != 75 || result.Issues[74].Key != "TU-75" {
+		t.Errorf("Expected the first 75 issues, got %d", len(result.Issues))
+	}
+	if requests != 2 {
+		t.Errorf("Expected %d requests, got %d", 2, requests)
+	}
+}
+
+func TestFetchIssuesLimitCountsDistinctIssues(t *testing.T) {
+	// TU-1 is served twice on the first page, as when a reshuffle moves it.
+	allIssues := []Issue{{Key: "TU-1"}, {Key: "TU-1"}, {Key: "TU-2"}, {Key: "TU-3"}, {Key: "TU-4"}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		end := min(startAt+2, len(allIssues))
+
+		response := IssueResponse{StartAt: startAt, MaxResults: 2, Total: len(allIssues), Issues: allIssues[startAt:end]}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	result, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, "", FetchOptions{MaxResults: 2, Limit: 3})
+	if err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+
+	var keys []string
+	for _, issue := range result.Issues {
+		keys = append(keys, issue.Key)
+	}
+	if got := strings.Join(keys, ","); got != "TU-1,TU-2,TU-3" {
+		t.Errorf("Expected 3 distinct issues, got %s", got)
+	}
+	if result.Cursor != "4" {
+		t.Errorf("Expected the cursor to point past TU-3, got %q", result.Cursor)
+	}
+}
+
+func TestStableOrderJQL(t *testing.T) {
+	tests := map[string]string{
+		"project = TU":                                  "project = TU",
+		"project = TU ORDER BY priority DESC":           "project = TU ORDER BY priority DESC, key ASC",
+		"project = TU order by rank, key DESC":          "project = TU order by rank, key DESC",
+		"project = TU ORDER BY created, issuekey":       "project = TU ORDER BY created, issuekey",
+		`summary ~ "key" ORDER BY updated`:              `summary ~ "key" ORDER BY updated, key ASC`,
+		"ORDER BY priority":                             "ORDER BY priority, key ASC",
+		`summary ~ "sort order by date"`:                `summary ~ "sort order by date"`,
+		`summary ~ "order by id" ORDER BY rank`:         `summary ~ "order by id" ORDER BY rank, key ASC`,
+		"project = TU AND id > 10 ORDER BY created":     "project = TU AND id > 10 ORDER BY created, key ASC",
+		`project = TU ORDER BY "Story Points", ID DESC`: `project = TU ORDER BY "Story Points", ID DESC`,
+		`project = TU ORDER BY "Task id" DESC`:          `project = TU ORDER BY "Task id" DESC, key ASC`,
+	}
+
+	for jql, expected := range tests {
+		if got := stableOrderJQL(jql); got != expected {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+	}
+}
+
+func TestBuildSearchJQLURL(t *testing.T) {
+	parsed, err := url.Parse(buildSearchJQLURL("http://example.com", "project = TU", "abc==", 25))
+	if err != nil {
+		t.Fatalf("Error parsing URL: %v", err)
+	}
+
+	if parsed.Path != "/rest/api/3/search/jql" {
+		t.Errorf("Expected path to be %s, got %s", "/rest/api/3/search/jql", parsed.Path)
+	}
+
+	if got := parsed.Query().Get("nextPageToken"); got != "abc==" {
+		t.Errorf("Expected nextPageToken to be %s, got %s", "abc==", got)
+	}
+
+	if _, err := url.Parse(buildSearchJQLURL("http://example.com", "project = TU", "", 25)); err != nil {
+		t.Fatalf("Error parsing URL: %v", err)
+	}
+	if strings.Contains(buildSearchJQLURL("http://example.com", "project = TU", "", 25), "nextPageToken") {
+		t.Error("Expected no nextPageToken on the first page")
+	}
+}
+
+func TestIssueCustomFields(t *testing.T) {
+	data := []byte(`{"key": "TU-1", "fields": {
+		"summary": "Bet",
+		"customfield_10506": {"value": "Committed", "id": "11755"},
+		"customfield_10016": 5.0,
+		"customfield_10020": null
+	}}`)
+
+	var issue Issue
+	if err := json.Unmarshal(data, &issue); err != nil {
+		t.Fatalf("Error unmarshalling issue: %v", err)
+	}
+
+	if issue.Fields.Summary != "Bet" {
+		t.Errorf("Expected summary to be %s, got %s", "Bet", issue.Fields.Summary)
+	}
+
+	if got := issue.CustomFieldString("customfield_10506"); got != "Committed" {
+		t.Errorf("Expected option value %s, got %s", "Committed", got)
+	}
+
+	if got := issue.CustomFieldString("customfield_10016"); got != "5.0" {
+		t.Errorf("Expected number %s, got %s", "5.0", got)
+	}
+
+	if got := issue.CustomFieldString("customfield_10020"); got != "" {
+		t.Errorf("Expected null field to be empty, got %s", got)
+	}
+
+	encoded, err := json.Marshal(issue)
+	if err != nil {
+		t.Fatalf("Error marshalling issue: %v", err)
+	}
+
+	var roundTripped Issue
+	if err := json.Unmarshal(encoded, &roundTripped); err != nil {
+		t.Fatalf("Error unmarshalling issue: %v", err)
+	}
+
+	if got := roundTripped.CustomFieldString("customfield_10506"); got != "Committed" {
+		t.Errorf("Expected custom field to survive a round trip, got %s", got)
+	}
+}
+
+func TestJiraClientRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+	client.Limiter = rate.NewLimiter(20, 1)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_, _, err := client.UpdateCustomField(context.Background(), "TU-1", "customfield_10506", CustomFieldOption("11755"))
+		if err != nil {
+			t.Fatalf("Error updating custom field: %v", err)
+		}
+	}
+
+	// The first request is free; each of the other four waits 50ms.
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected 5 requests at 20rps to take at least %v, took %v", 200*time.Millisecond, elapsed)
+	}
+}
+
+func TestFetchIssuesResultTotal(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := IssueResponse{Total: 2, Issues: []Issue{{Key: "TU-1"}, {Key: "TU-2"}}}
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+
+	defer ts.Close()
+
+	result, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, "")
+	if err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+
+	if result.Total != 2 {
+		t.Errorf("Expected total %d, got %d", 2, result.Total)
+	}
+
+	if result.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, result.StatusCode)
+	}
+}
+
+func TestAuthErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		sentinel error
+	}{
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			_, err := w.Write([]byte(`{"errorMessages":["You do not have permission"]}`))
+			if err != nil {
+				t.Fatalf("Error writing response: %v", err)
+			}
+		}))
+
+		_, fetchErr := fetchIssues(context.Background(), "encodedCredentials", ts.URL, "")
+		_, _, updateErr := updateCustomField(context.Background(), "TU-1", "customfield_10506", CustomFieldOption("11755"), "encodedCredentials", ts.URL)
+		ts.Close()
+
+		for _, err := range []error{fetchErr, updateErr} {
+			if !errors.Is(err, tt.sentinel) {
+				t.Errorf("Expected errors.Is(err, %v) for status %d, got %v", tt.sentinel, tt.status, err)
+			}
+
+			if err != nil && !strings.Contains(err.Error(), "You do not have permission") {
+				t.Errorf("Expected error to include the body, got %q", err)
+			}
+		}
+	}
+}
+
+func TestJiraClientBearerAuth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer personalAccessToken" {
+			t.Errorf("Expected Authorization header to be %s, got %s", "Bearer personalAccessToken", got)
+		}
+		w.Write([]byte(`{"total": 0, "issues": []}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewJiraClient(ts.URL, "personalAccessToken", nil)
+	if err != nil {
+		t.Fatalf("Error creating client: %v", err)
+	}
+	client.AuthType = BearerAuth
+
+	if _, err := client.FetchIssues(context.Background(), ""); err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+}
+
+func TestUpdateCustomFieldRejectsInvalidKeys(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected no request for an invalid key, got %s %s", r.Method, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	for _, key := range []string{"", "tu-1", "TU-1/../../field", "TU"} {
+		_, _, err := updateCustomField(context.Background(), key, "customfield_10506", CustomFieldOption("11755"), "encodedCredentials", ts.URL)
+		if err == nil {
+			t.Errorf("Expected an error for key %q", key)
+		}
+	}
+}
+
+func TestValidateIssueKey(t *testing.T) {
+	for _, key := range []string{"TU-1", "AB2-345", "A-1", "OPS_2-1"} {
+		if err := validateIssueKey(key); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", key, err)
+		}
+	}
+}
+
+func TestFetchIssue(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/2/issue/TU-1":
+			if got := r.URL.Query().Get("fields"); got != "summary,status" {
+				t.Errorf("Expected fields summary,status, got %q", got)
+			}
+			w.Write([]byte(`{"key": "TU-1", "fields": {"summary": "Single bet", "status": {"name": "To Do"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errorMessages": ["Issue does not exist or you do not have permission to see it."]}`))
+		}
+	}))
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+	issue, err := client.FetchIssue(context.Background(), "TU-1", "summary", "status")
+	if err != nil {
+		t.Fatalf("Error fetching issue: %v", err)
+	}
+
+	if issue.Key != "TU-1" || issue.Fields.Summary != "Single bet" || issue.StatusName() != "To Do" {
+		t.Errorf("Expected TU-1 Single bet in To Do, got %+v", issue)
+	}
+
+	_, err = client.FetchIssue(context.Background(), "TU-404")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestFetchIssuesByKeys(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		jql := r.URL.Query().Get("jql")
+		keys := strings.Split(strings.TrimSuffix(strings.TrimPrefix(jql, "key IN ("), ")"), ", ")
+		if len(keys) > maxKeysPerSearch {
+			t.Errorf("Expected at most %d keys per search, got %d", maxKeysPerSearch, len(keys))
+		}
+
+		// Return the chunk in reverse to check the input order is restored.
+		response := IssueResponse{Total: len(keys)}
+		for i := len(keys) - 1; i >= 0; i-- {
+			response.Issues = append(response.Issues, Issue{Key: keys[i]})
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	var keys []string
+	for i := 1; i <= 150; i++ {
+		keys = append(keys, "TU-"+strconv.Itoa(i))
+	}
+
+	issues, err := fetchIssuesByKeys(context.Background(), keys, "encodedCredentials", ts.URL)
+	if err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+
+	if len(issues) != len(keys) {
+		t.Fatalf("Expected %d issues, got %d", len(keys), len(issues))
+	}
+	for i, issue := range issues {
+		if issue.Key != keys[i] {
+			t.Errorf("Expected issue %d to be %s, got %s", i, keys[i], issue.Key)
+		}
+	}
+}
+
+func TestFetchIssuesExpand(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("expand"); got != "renderedFields,changelog" {
+			t.Errorf("Expected expand to be %s, got %s", "renderedFields,changelog", got)
+		}
+		w.Write([]byte(`{"total": 1, "issues": [{"key": "TU-1", "fields": {"summary": "Bet"},
+			"renderedFields": {"description": "<p>Bet</p>"},
+			"changelog": {"total": 1, "histories": [{"id": "100", "created": "2024-05-01T10:00:00.000+0000",
+				"items": [{"field": "status", "fromString": "To Do", "toString": "Done"}]}]}}]}`))
+	}))
+	defer ts.Close()
+
+	result, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, "", FetchOptions{Expand: []string{"renderedFields", "changelog"}})
+	if err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+
+	issue := result.Issues[0]
+	if issue.Changelog == nil || len(issue.Changelog.Histories) != 1 || issue.Changelog.Histories[0].Items[0].ToString != "Done" {
+		t.Errorf("Expected the changelog to be decoded, got %+v", issue.Changelog)
+	}
+	if string(issue.RenderedFields["description"]) != `"<p>Bet</p>"` {
+		t.Errorf("Expected the rendered description, got %s", issue.RenderedFields["description"])
+	}
+}
+
+func TestFetchIssuesWithoutExpand(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["expand"]; ok {
+			t.Errorf("Expected no expand parameter, got %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"total": 0, "issues": []}`))
+	}))
+	defer ts.Close()
+
+	if _, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, ""); err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+}
+
+func TestFetchIssuesFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   []string
+		expected string
+	}{
+		{"default", nil, "key,summary,status,updated"},
+		{"scoped", []string{"summary", "labels"}, "summary,labels"},
+		{"all", []string{"*all"}, "*all"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.Query()["fields"]; len(got) != 1 || got[0] != tt.expected {
+					t.Errorf("Expected fields to be %s, got %v", tt.expected, got)
+				}
+				w.Write([]byte(`{"total": 0, "issues": []}`))
+			}))
+			defer ts.Close()
+
+			if _, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, "", FetchOptions{Fields: tt.fields}); err != nil {
+				t.Fatalf("Error fetching issues: %v", err)
+			}
+		})
+	}
+}
+
+func TestFullJitter(t *testing.T) {
+	var mu sync.Mutex
+	draw := func(seed uint64) []time.Duration {
+		rng := rand.New(rand.NewPCG(seed, seed))
+		var delays []time.Duration
+		for attempt := 0; attempt < 4; attempt++ {
+			for range 25 {
+				delays = append(delays, fullJitter(100*time.Millisecond<<attempt, rng, &mu))
+			}
+		}
+		return delays
+	}
+
+	delays := draw(42)
+	distinct := map[time.Duration]bool{}
+	for i, delay := range delays {
+		ceiling := 100 * time.Millisecond << (i / 25)
+		if delay < 0 || delay > ceiling {
+			t.Errorf("Expected delay %d to be within [0, %v], got %v", i, ceiling, delay)
+		}
+		distinct[delay] = true
+	}
+	if len(distinct) < len(delays)/2 {
+		t.Errorf("Expected jittered delays to vary, got %d distinct of %d", len(distinct), len(delays))
+	}
+
+	again := draw(42)
+	for i := range delays {
+		if delays[i] != again[i] {
+			t.Fatalf("Expected the same seed to give the same delays, got %v and %v at %d", delays[i], again[i], i)
+		}
+	}
+
+	if got := fullJitter(0, nil, &mu); got != 0 {
+		t.Errorf("Expected no delay for a zero backoff, got %v", got)
+	}
+}
+
+func TestHTTPErrorRetryable(t *testing.T) {
+	tests := []struct {
+		status    int
+		retryable bool
+	}{
+		{http.StatusBadRequest, false},
+		{http.StatusUnauthorized, false},
+		{http.StatusNotFound, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusServiceUnavailable, true},
+	}
+
+	for _, tt := range tests {
+		err := &HTTPError{StatusCode: tt.status}
+		if got := err.Retryable(); got != tt.retryable {
+			t.Errorf("Expected Retryable() for status %d to be %v, got %v", tt.status, tt.retryable, got)
+		}
+	}
+}
+
+func TestFetchIssuesReturnsHTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errorMessages":["Bad JQL"]}`))
+	}))
+	defer ts.Close()
+
+	_, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, "")
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Expected an *HTTPError, got %T", err)
+	}
+	if httpErr.StatusCode != http.StatusBadRequest || httpErr.Body != `{"errorMessages":["Bad JQL"]}` {
+		t.Errorf("Expected the status and body to be kept, got %+v", httpErr)
+	}
+	if httpErr.Retryable() {
+		t.Error("Expected a 400 not to be retryable")
+	}
+}
+
+func TestHTTPErrorJiraMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected string
+	}{
+		{
+			"field errors",
+			http.StatusBadRequest,
+			`{"errorMessages":[],"errors":{"customfield_10506":"Option id '99999' is not valid","summary":"You must specify a summary of the issue."}}`,
+			`HTTP error! Status: 400, field customfield_10506: Option id '99999' is not valid; field summary: You must specify a summary of the issue.`,
+		},
+		{
+			"messages and field errors",
+			http.StatusBadRequest,
+			`{"errorMessages":["Field 'priority' cannot be set."],"errors":{"customfield_10506":"option id is not valid"}}`,
+			`HTTP error! Status: 400, Field 'priority' cannot be set.; field customfield_10506: option id is not valid`,
+		},
+		{"no messages", http.StatusBadRequest, `{"errorMessages":[],"errors":{}}`, `HTTP error! Status: 400, Body: {"errorMessages":[],"errors":{}}`},
+		{"not json", http.StatusBadRequest, `<html>Bad Request</html>`, `HTTP error! Status: 400, Body: <html>Bad Request</html>`},
+		{"other status", http.StatusNotFound, `{"errorMessages":["Issue does not exist"]}`, `HTTP error! Status: 404, Body: {"errorMessages":["Issue does not exist"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := httpError(tt.status, tt.body).Error(); got != tt.expected {
+				t.Errorf("Expected error %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestJoinURL(t *testing.T) {
+	tests := []struct {
+		baseURL  string
+		expected string
+	}{
+		{"https://example.atlassian.net", "https://example.atlassian.net/rest/api/2/issue/TU-1"},
+		{"https://example.atlassian.net/", "https://example.atlassian.net/rest/api/2/issue/TU-1"},
+		{"https://jira.corp.com/jira", "https://jira.corp.com/jira/rest/api/2/issue/TU-1"},
+		{"https://jira.corp.com/jira/", "https://jira.corp.com/jira/rest/api/2/issue/TU-1"},
+	}
+
+	for _, tt := range tests {
+		if got := joinURL(tt.baseURL, "/rest/api/2/issue/TU-1"); got != tt.expected {
+			t.Errorf("Expected joinURL(%q) to be %s, got %s", tt.baseURL, tt.expected, got)
+		}
+	}
+}
+
+func TestBuildSearchURLWithContextPath(t *testing.T) {
+	searchURL := buildSearchURL("https://jira.corp.com/jira", "project = TU", 0, 50)
+
+	parsed, err := url.Parse(searchURL)
+	if err != nil {
+		t.Fatalf("Error parsing URL: %v", err)
+	}
+
+	if parsed.Path != "/jira/rest/api/2/search" {
+		t.Errorf("Expected path to be %s, got %s", "/jira/rest/api/2/search", parsed.Path)
+	}
+
+	if got := parsed.Query().Get("jql"); got != "project = TU" {
+		t.Errorf("Expected jql to be %q, got %q", "project = TU", got)
+	}
+}
+
+func TestParseBaseURL(t *testing.T) {
+	for _, raw := range []string{"", "example.atlassian.net", "ftp://example.atlassian.net", "https://", "https://example.atlassian.net?x=1", "://bad"} {
+		if _, err := ParseBaseURL(raw); err == nil {
+			t.Errorf("Expected an error for %q", raw)
+		}
+	}
+
+	got, err := ParseBaseURL("https://jira.corp.com/jira/")
+	if err != nil {
+		t.Fatalf("Error parsing base URL: %v", err)
+	}
+	if got != "https://jira.corp.com/jira" {
+		t.Errorf("Expected %s, got %s", "https://jira.corp.com/jira", got)
+	}
+}
+
+func TestNewJiraClientRejectsInvalidBaseURL(t *testing.T) {
+	if _, err := NewJiraClient("example.atlassian.net", "encodedCredentials", nil); err == nil {
+		t.Error("Expected an error for a base URL without a scheme")
+	}
+}
+
+func TestJiraClientExtraHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Proxy-Token"); got != "proxyToken" {
+			t.Errorf("Expected X-Proxy-Token header to be %s, got %s", "proxyToken", got)
+		}
+
+		if got := r.Header.Get("X-Atlassian-Token"); got != "no-check" {
+			t.Errorf("Expected X-Atlassian-Token header to be %s, got %s", "no-check", got)
+		}
+
+		if got := r.Header.Get("Authorization"); got != "Basic encodedCredentials" {
+			t.Errorf("Expected Authorization header to be %s, got %s", "Basic encodedCredentials", got)
+		}
+
+		if got := r.Header.Get("Accept"); got != "application/json" {
+			t.Errorf("Expected Accept header to be %s, got %s", "application/json", got)
+		}
+		w.Write([]byte(`{"total": 0, "issues": []}`))
+	}))
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+	client.Headers = map[string]string{
+		"X-Proxy-Token":     "proxyToken",
+		"X-Atlassian-Token": "no-check",
+		"Authorization":     "Basic somebodyElse",
+		"Accept":            "text/html",
+	}
+
+	if _, err := client.FetchIssues(context.Background(), ""); err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+}
+
+func TestFetchIssuesStopsWhenTotalIsWrong(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 5 {
+			t.Fatalf("Expected pagination to stop, got %d requests", requests)
+		}
+
+		response := IssueResponse{Total: 10, Issues: []Issue{}}
+		if r.URL.Query().Get("startAt") == "0" {
+			response.Issues = []Issue{{Key: "TU-1"}, {Key: "TU-2"}}
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	client := newTestJiraClient(t, ts.URL)
+	client.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+
+	result, err := client.FetchIssues(context.Background(), "", FetchOptions{MaxResults: 2})
+	if err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+
+	if len(result.Issues) != 2 || requests != 2 {
+		t.Errorf("Expected 2 issues from 2 requests, got %d from %d", len(result.Issues), requests)
+	}
+
+	if !strings.Contains(buf.String(), "level=WARN") || !strings.Contains(buf.String(), "collected=2") {
+		t.Errorf("Expected a warning about the short result, got %s", buf.String())
+	}
+}
+
+func TestUpdateFields(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/rest/api/2/issue/TU-1" {
+			t.Errorf("Expected PUT /rest/api/2/issue/TU-1, got %s %s", r.Method, r.URL.Path)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		assertJSON(t, json.RawMessage(body), `{"fields": {
+			"customfield_10506": {"id": "11755"},
+			"customfield_10016": 5,
+			"summary": "Renamed bet"
+		}}`)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	statusCode, _, err := updateFields(context.Background(), "TU-1", map[string]interface{}{
+		"customfield_10506": CustomFieldOption("11755"),
+		"customfield_10016": 5,
+		"summary":           "Renamed bet",
+	}, "encodedCredentials", ts.URL)
+	if err != nil {
+		t.Fatalf("Error updating fields: %v", err)
+	}
+
+	if statusCode != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, statusCode)
+	}
+}
+
+func TestJiraClientOverridesContentHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept"); got != "application/octet-stream" {
+			t.Errorf("Expected Accept header to be %s, got %s", "application/octet-stream", got)
+		}
+
+		if got := r.Header.Get("Content-Type"); got != "text/plain" {
+			t.Errorf("Expected Content-Type header to be %s, got %s", "text/plain", got)
+		}
+
+		if got := r.Header.Get("Authorization"); got != "Basic encodedCredentials" {
+			t.Errorf("Expected Authorization header to be %s, got %s", "Basic encodedCredentials", got)
+		}
+	}))
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+	resp, err := client.doWith(context.Background(), "GET", ts.URL, nil, contentHeaders{Accept: "application/octet-stream", ContentType: "text/plain"})
+	if err != nil {
+		t.Fatalf("Error sending request: %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Notion property types supported by buildNotionProperties.
+const (
+	NotionTitle    = "title"
+	NotionRichText = "rich_text"
+	NotionSelect   = "select"
+	NotionDate     = "date"
+	// NotionMultiSelect writes a list field, "labels" or "components", as one
+	// option per distinct value. Notion creates missing options on the fly.
+	NotionMultiSelect = "multi_select"
+	NotionURL         = "url"
+	// NotionNumber writes a numeric field, such as "story_points". Unset
+	// values leave the property out rather than writing 0.
+	NotionNumber = "number"
+	// NotionRelation links to the page of the Jira issue named by the field,
+	// such as "parent" or "epic", or to the pages of the issues it lists, such
+	// as "links:blocks". It is filled in by resolveRelations rather than
+	// buildNotionProperties, since it needs the related pages' ids.
+	NotionRelation = "relation"
+	// NotionPeople writes the "watchers" or "voters" of an issue as Notion
+	// users; see usersProperty.
+	NotionPeople = "people"
+)
+
+// FieldMapping copies one Jira field into one Notion database property.
+type FieldMapping struct {
+	JiraField      string `json:"jiraField" yaml:"jiraField"`
+	NotionProperty string `json:"notionProperty" yaml:"notionProperty"`
+	NotionType     string `json:"notionType" yaml:"notionType"`
+	// Values renames Jira values on the way to Notion, such as the status
+	// "In Progress" to a select option named "Doing". Values it doesn't list
+	// are written unchanged unless StrictValues is set, which makes them an
+	// error instead. Numbers and dates are never renamed.
+	Values       map[string]string `json:"values,omitempty" yaml:"values,omitempty"`
+	StrictValues bool              `json:"strictValues,omitempty" yaml:"strictValues,omitempty"`
+	// FallbackProperty, for a NotionPeople mapping, names a rich_text
+	// property that lists the users with no Notion user in NotionUserIDs,
+	// who can't appear in the people property itself.
+	FallbackProperty string `json:"fallbackProperty,omitempty" yaml:"fallbackProperty,omitempty"`
+}
+
+// notionValue returns the Notion value m.Values gives value.
+func (m FieldMapping) notionValue(value string) (string, error) {
+	if renamed, ok := m.Values[value]; ok {
+		return renamed, nil
+	}
+	// An empty value means the field is unset; there is nothing to rename.
+	if m.StrictValues && value != "" {
+		return "", fmt.Errorf("no Notion value for %s %q of property %q", m.JiraField, value, m.NotionProperty)
+	}
+	return value, nil
+}
+
+// defaultFieldMapping is used when no mapping is configured. The Jira key
+// needs no mapping; see NotionClient.JiraKeyProperty.
+var defaultFieldMapping = []FieldMapping{
+	{JiraField: "summary", NotionProperty: "Name", NotionType: NotionTitle},
+	{JiraField: "status", NotionProperty: "Status", NotionType: NotionSelect},
+	{JiraField: "assignee", NotionProperty: "Assignee", NotionType: NotionRichText},
+	{JiraField: "priority", NotionProperty: "Priority", NotionType: NotionSelect},
+	{JiraField: "updated", NotionProperty: "Updated", NotionType: NotionDate},
+}
+
+const (
+	// defaultJiraURLProperty is the default PageMetadata.JiraURLProperty.
+	defaultJiraURLProperty = "Jira URL"
+	// defaultLastSyncedProperty is the PageMetadata.LastSyncedProperty the
+	// command stamps unless SYNC_LAST_SYNCED_PROPERTY says otherwise.
+	defaultLastSyncedProperty = "Last Synced"
+)
+
+// PageMetadata configures the properties a NotionSyncer writes about each
+// page besides its mapped fields and keys.
+type PageMetadata struct {
+	// JiraWebBaseURL is where people open Jira in a browser, such as
+	// https://example.atlassian.net. It can differ from the API base, e.g.
+	// behind a proxy. When set, each page links back to its issue in the URL
+	// property JiraURLProperty, or defaultJiraURLProperty if that is "".
+	JiraWebBaseURL  string
+	JiraURLProperty string
+	// LastSyncedProperty, when set, is a date property stamped with the time
+	// of each write, so stale pages stand out in Notion.
+	LastSyncedProperty string
+	// Now is the clock for LastSyncedProperty. A nil Now means time.Now.
+	Now func() time.Time
+}
+
+// jiraURLProperty is the property addJiraURL writes, or "" if pages aren't
+// linked back to Jira.
+func (m PageMetadata) jiraURLProperty() string {
+	switch {
+	case m.JiraWebBaseURL == "":
+		return ""
+	case m.JiraURLProperty == "":
+		return defaultJiraURLProperty
+	}
+	return m.JiraURLProperty
+}
+
+// addJiraURL links the page for issue back to it in Jira, when
+// JiraWebBaseURL is set.
+func (m PageMetadata) addJiraURL(properties map[string]interface{}, issue Issue) {
+	if name := m.jiraURLProperty(); name != "" {
+		properties[name] = map[string]interface{}{"url": browseURL(m.JiraWebBaseURL, issue.Key)}
+	}
+}
+
+// stampLastSynced dates properties with the current time in
+// LastSyncedProperty, when it is set.
+func (m PageMetadata) stampLastSynced(properties map[string]interface{}) {
+	if m.LastSyncedProperty == "" {
+		return
+	}
+	now := m.Now
+	if now == nil {
+		now = time.Now
+	}
+	properties[m.LastSyncedProperty] = map[string]interface{}{"date": map[string]interface{}{"start": now().Format(time.RFC3339)}}
+}
+
+// browseURL returns the page that shows issueKey in Jira's web UI.
+func browseURL(webBaseURL, issueKey string) string {
+	return joinURL(webBaseURL, "/browse/"+url.PathEscape(issueKey))
+}
+
+// jiraFieldValue returns the value of a Jira field by the name used in a
+// FieldMapping. The boolean is false for fields the sync doesn't know about.
+func jiraFieldValue(issue Issue, field string) (string, bool) {
+	switch field {
+	case "key":
+		return issue.Key, true
+	case "summary":
+		return issue.Fields.Summary, true
+	case "status":
+		return issue.StatusName(), true
+	case "assignee":
+		return issue.AssigneeName(), true
+	case "priority":
+		return issue.PriorityName(), true
+	case "updated":
+		return issue.Fields.Updated, true
+	case "duedate":
+		return issue.Fields.DueDate, true
+	case "parent":
+		return issue.ParentKey(), true
+	case "epic":
+		return issue.EpicKey(), true
+	case "sprint":
+		return issue.SprintName(SprintFieldID), true
+	}
+	return "", false
+}
+
+// StoryPointsFieldID is the custom field behind the "story_points" mapping
+// field. customfield_10016 is Jira Cloud's story point estimate; look up
+// others with GetCustomFieldID(ctx, "Story Points").
+var StoryPointsFieldID = "customfield_10016"
+
+// jiraFieldNumber is jiraFieldValue for numeric fields, which map to
+// NotionNumber: "story_points" or any customfield_* id. The value is nil
+// when the field is unset, as for an unestimated issue, or isn't a number,
+// which is logged to logger.
+func jiraFieldNumber(issue Issue, field string, logger *slog.Logger) (*float64, bool) {
+	switch {
+	case field == "story_points":
+		field = StoryPointsFieldID
+	case !strings.HasPrefix(field, "customfield_"):
+		return nil, false
+	}
+
+	var value *float64
+	if raw, ok := issue.Fields.Custom[field]; ok {
+		if err := json.Unmarshal(raw, &value); err != nil {
+			logger.Warn("Jira field is not a number", "field", field, "issueKey", issue.Key, "value", string(raw))
+			return nil, true
+		}
+	}
+	return value, true
+}
+
+// jiraFieldID returns the id under which Jira lists the field a FieldMapping
+// calls name.
+func jiraFieldID(name string) string {
+	switch name {
+	case "key":
+		return "issuekey"
+	case "sprint":
+		return SprintFieldID
+	case "story_points":
+		return StoryPointsFieldID
+	case "epic":
+		return "parent"
+	case "watchers":
+		return "watches"
+	case "voters":
+		return "votes"
+	}
+	if strings.HasPrefix(name, "links:") {
+		return "issuelinks"
+	}
+	return name
+}
+
+// mappingFields returns the Jira fields to fetch for mapping: defaultFields
+// and issuetype, plus the field behind each mapping.
+func mappingFields(mapping []FieldMapping) []string {
+	// issuetype picks each page's icon; see issuePageOptions.
+	fields := append([]string{"issuetype"}, defaultFields...)
+	for _, m := range mapping {
+		// The key comes with every issue and is already in defaultFields.
+		field := m.JiraField
+		if field != "key" {
+			field = jiraFieldID(field)
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+		if m.JiraField == "epic" && !slices.Contains(fields, EpicLinkFieldID) {
+			fields = append(fields, EpicLinkFieldID)
+		}
+	}
+	return fields
+}
+
+// jiraFieldValues is jiraFieldValue for list fields, which map to
+// NotionMultiSelect, or for "links:<relation>" to NotionRelation.
+func jiraFieldValues(issue Issue, field string) ([]string, bool) {
+	if relation, ok := strings.CutPrefix(field, "links:"); ok {
+		return issue.LinkedKeys(relation), true
+	}
+
+	switch field {
+	case "labels":
+		return issue.Fields.Labels, true
+	case "components":
+		names := make([]string, 0, len(issue.Fields.Components))
+		for _, component := range issue.Fields.Components {
+			names = append(names, component.Name)
+		}
+		return names, true
+	}
+	return nil, false
+}
+
+// buildNotionProperties turns an issue into the properties payload Notion
+// expects for a page, following mapping. Mappings that name an unknown Jira
+// field or Notion type are skipped with a warning to logger. The sync adds the issue's
+// keys with NotionClient.addIssueKeys and its PageMetadata on top. It fails
+// only on a value a mapping with StrictValues can't rename.
+func buildNotionProperties(issue Issue, mapping []FieldMapping, logger *slog.Logger) (map[string]interface{}, error) {
+	properties := map[string]interface{}{}
+
+	for _, m := range mapping {
+		if m.NotionType == NotionRelation || userListField(m.JiraField) {
+			continue
+		}
+
+		if m.NotionType == NotionMultiSelect {
+			values, ok := jiraFieldValues(issue, m.JiraField)
+			if !ok {
+				logger.Warn("skipping unknown Jira list field", "field", m.JiraField, "property", m.NotionProperty, "issueKey", issue.Key)
+				continue
+			}
+			renamed := make([]string, 0, len(values))
+			for _, value := range values {
+				value, err := m.notionValue(value)
+				if err != nil {
+					return nil, err
+				}
+				renamed = append(renamed, value)
+			}
+			properties[m.NotionProperty] = multiSelectProperty(renamed)
+			continue
+		}
+
+		if m.NotionType == NotionNumber {
+			value, ok := jiraFieldNumber(issue, m.JiraField, logger)
+			if !ok {
+				logger.Warn("skipping unknown Jira number field", "field", m.JiraField, "property", m.NotionProperty, "issueKey", issue.Key)
+				continue
+			}
+			if value != nil {
+				properties[m.NotionProperty] = map[string]interface{}{"number": *value}
+			}
+			continue
+		}
+
+		value, ok := jiraFieldValue(issue, m.JiraField)
+		if !ok {
+			logger.Warn("skipping unknown Jira field", "field", m.JiraField, "property", m.NotionProperty, "issueKey", issue.Key)
+			continue
+		}
+
+		// Issues without a due date leave the property out.
+		if m.JiraField == "duedate" {
+			if value == "" {
+				continue
+			}
+			if _, err := time.Parse(time.DateOnly, value); err != nil {
+				logger.Warn("skipping due date that is not YYYY-MM-DD", "field", m.JiraField, "issueKey", issue.Key, "value", value)
+				continue
+			}
+		} else if m.NotionType != NotionDate {
+			var err error
+			if value, err = m.notionValue(value); err != nil {
+				return nil, err
+			}
+		}
+
+		property, ok := notionPropertyValue(m.NotionType, value)
+		if !ok {
+			logger.Warn("skipping unsupported Notion type", "type", m.NotionType, "property", m.NotionProperty, "issueKey", issue.Key)
+			continue
+		}
+
+		properties[m.NotionProperty] = property
+	}
+
+	return properties, nil
+}
+
+// addIssueKeys writes the issue's externalID to c.JiraKeyProperty, as
+// rich_text unless mapping writes the key there itself, as a title for
+// instance, and its id to c.JiraIDProperty when set.
+func (c *NotionClient) addIssueKeys(properties map[string]interface{}, issue Issue, mapping []FieldMapping) {
+	if _, ok := properties[c.JiraKeyProperty]; !ok || c.JiraInstance != "" {
+		keyType := NotionRichText
+		for _, m := range mapping {
+			if m.NotionProperty == c.JiraKeyProperty && m.NotionType == NotionTitle {
+				keyType = NotionTitle
+			}
+		}
+		properties[c.JiraKeyProperty] = map[string]interface{}{keyType: richText(externalID(c.JiraInstance, issue.Key))}
+	}
+
+	if c.JiraIDProperty != "" && issue.ID != "" {
+		properties[c.JiraIDProperty] = map[string]interface{}{NotionRichText: richText(externalID(c.JiraInstance, issue.ID))}
+	}
+}
+
+// supportedNotionType reports whether buildNotionProperties,
+// resolveRelations or userListProperties can write notionType.
+func supportedNotionType(notionType string) bool {
+	switch notionType {
+	case NotionTitle, NotionRichText, NotionSelect, NotionDate, NotionMultiSelect, NotionURL, NotionNumber, NotionRelation, NotionPeople:
+		return true
+	}
+	return false
+}
+
+func notionPropertyValue(notionType, value string) (map[string]interface{}, bool) {
+	switch notionType {
+	case NotionTitle, NotionRichText:
+		return map[string]interface{}{notionType: richText(value)}, true
+	case NotionSelect:
+		if value == "" {
+			return map[string]interface{}{"select": nil}, true
+		}
+		return map[string]interface{}{"select": map[string]interface{}{"name": value}}, true
+	case NotionDate:
+		if value == "" {
+			return map[string]interface{}{"date": nil}, true
+		}
+		if t, err := parseJiraTime(value); err == nil {
+			value = t.Format(time.RFC3339)
+		}
+		return map[string]interface{}{"date": map[string]interface{}{"start": value}}, true
+	case NotionURL:
+		if value == "" {
+			return map[string]interface{}{"url": nil}, true
+		}
+		return map[string]interface{}{"url": value}, true
+	case NotionRelation:
+		return relationProperty(value), true
+	}
+	return nil, false
+}
+
+// multiSelectProperty makes one option per distinct value, trimmed, so stray
+// whitespace doesn't create near-duplicate options. No values clears the
+// property.
+func multiSelectProperty(values []string) map[string]interface{} {
+	options := []interface{}{}
+	seen := map[string]bool{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		options = append(options, map[string]interface{}{"name": value})
+	}
+	return map[string]interface{}{"multi_select": options}
+}
+
+// relationProperty links to the pages with pageIDs, skipping "". No ids
+// clear the relation.
+func relationProperty(pageIDs ...string) map[string]interface{} {
+	related := []interface{}{}
+	for _, pageID := range pageIDs {
+		if pageID != "" {
+			related = append(related, map[string]interface{}{"id": pageID})
+		}
+	}
+	return map[string]interface{}{"relation": related}
+}
+
+// resolveRelations builds the NotionRelation properties in mapping by finding
+// the page of each related Jira key in databaseID. A related issue that has
+// no page yet is left out and its key returned in missing, so the caller can
+// retry if that page is written later; for a list field such as
+// "links:blocks" the pages that do exist are linked meanwhile.
+func resolveRelations(ctx context.Context, notion *NotionClient, databaseID string, issue Issue, mapping []FieldMapping) (properties map[string]interface{}, missing []string, err error) {
+	properties = map[string]interface{}{}
+
+	for _, m := range mapping {
+		if m.NotionType != NotionRelation {
+			continue
+		}
+
+		if keys, ok := jiraFieldValues(issue, m.JiraField); ok {
+			pageIDs := make([]string, 0, len(keys))
+			for _, key := range keys {
+				pageID, err := notion.FindPageByJiraKey(ctx, databaseID, key)
+				if err != nil {
+					return nil, nil, err
+				}
+				if pageID == "" {
+					missing = append(missing, key)
+				}
+				pageIDs = append(pageIDs, pageID)
+			}
+			properties[m.NotionProperty] = relationProperty(pageIDs...)
+			continue
+		}
+
+		key, ok := jiraFieldValue(issue, m.JiraField)
+		if !ok {
+			notion.log().Warn("skipping unknown Jira field", "field", m.JiraField, "property", m.NotionProperty, "issueKey", issue.Key)
+			continue
+		}
+		if key == "" {
+			properties[m.NotionProperty] = relationProperty("")
+			continue
+		}
+
+		pageID, err := notion.FindPageByJiraKey(ctx, databaseID, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if pageID == "" {
+			missing = append(missing, key)
+			continue
+		}
+		properties[m.NotionProperty] = relationProperty(pageID)
+	}
+
+	return properties, missing, nil
+}
+
+func richText(content string) []interface{} {
+	if content == "" {
+		return []interface{}{}
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"type": "text",
+			"text": map[string]interface{}{"content": content},
+		},
+	}
+}
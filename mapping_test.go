@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testIssue() Issue {
+	return Issue{
+		Key: "TU-1",
+		Fields: IssueFields{
+			Summary: "Launch the onboarding bet",
+			Status:  &Status{Name: "In Progress"},
+			Updated: "2024-03-01T12:34:56.000+0000",
+		},
+	}
+}
+
+// mappedProperties is buildNotionProperties, failing the test on an error.
+func mappedProperties(t *testing.T, issue Issue, mapping []FieldMapping) map[string]interface{} {
+	t.Helper()
+
+	properties, err := buildNotionProperties(issue, mapping, discardLogger)
+	if err != nil {
+		t.Fatalf("Error building properties: %v", err)
+	}
+	return properties
+}
+
+func assertJSON(t *testing.T, got interface{}, expected string) {
+	t.Helper()
+
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("Error marshalling value: %v", err)
+	}
+
+	var gotValue, expectedValue interface{}
+	if err := json.Unmarshal(data, &gotValue); err != nil {
+		t.Fatalf("Error unmarshalling value: %v", err)
+	}
+	if err := json.Unmarshal([]byte(expected), &expectedValue); err != nil {
+		t.Fatalf("Error unmarshalling expected value: %v", err)
+	}
+
+	gotJSON, _ := json.Marshal(gotValue)
+	expectedJSON, _ := json.Marshal(expectedValue)
+	if string(gotJSON) != string(expectedJSON) {
+		t.Errorf("Expected %s, got %s", expectedJSON, gotJSON)
+	}
+}
+
+func TestBuildNotionPropertiesTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		mapping  FieldMapping
+		expected string
+	}{
+		{"title", FieldMapping{JiraField: "summary", NotionProperty: "Name", NotionType: NotionTitle}, `{"Name":{"title":[{"type":"text","text":{"content":"Launch the onboarding bet"}}]}}`},
+		{"rich_text", FieldMapping{JiraField: "key", NotionProperty: "Issue", NotionType: NotionRichText}, `{"Issue":{"rich_text":[{"type":"text","text":{"content":"TU-1"}}]}}`},
+		{"select", FieldMapping{JiraField: "status", NotionProperty: "Status", NotionType: NotionSelect}, `{"Status":{"select":{"name":"In Progress"}}}`},
+		{"empty select", FieldMapping{JiraField: "priority", NotionProperty: "Priority", NotionType: NotionSelect}, `{"Priority":{"select":null}}`},
+		{"date", FieldMapping{JiraField: "updated", NotionProperty: "Updated", NotionType: NotionDate}, `{"Updated":{"date":{"start":"2024-03-01T12:34:56Z"}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			properties := mappedProperties(t, testIssue(), []FieldMapping{tt.mapping})
+			assertJSON(t, properties, tt.expected)
+		})
+	}
+}
+
+func TestBuildNotionPropertiesLabels(t *testing.T) {
+	issue := testIssue()
+	issue.Fields.Labels = []string{"backend", " growth ", "q3", "backend"}
+	mapping := []FieldMapping{{JiraField: "labels", NotionProperty: "Labels", NotionType: NotionMultiSelect}}
+
+	assertJSON(t, mappedProperties(t, issue, mapping), `{"Labels":{"multi_select":[{"name":"backend"},{"name":"growth"},{"name":"q3"}]}}`)
+
+	issue.Fields.Labels = nil
+	assertJSON(t, mappedProperties(t, issue, mapping), `{"Labels":{"multi_select":[]}}`)
+}
+
+func TestBuildNotionPropertiesComponents(t *testing.T) {
+	var issue Issue
+	if err := json.Unmarshal([]byte(`{"key": "TU-1", "fields": {"components": [{"id": "10000", "name": "Billing"}, {"id": "10001", "name": "Onboarding"}, {"id": "10000", "name": "Billing"}]}}`), &issue); err != nil {
+		t.Fatalf("Error decoding issue: %v", err)
+	}
+	mapping := []FieldMapping{{JiraField: "components", NotionProperty: "Components", NotionType: NotionMultiSelect}}
+
+	assertJSON(t, mappedProperties(t, issue, mapping), `{"Components":{"multi_select":[{"name":"Billing"},{"name":"Onboarding"}]}}`)
+
+	issue.Fields.Components = nil
+	assertJSON(t, mappedProperties(t, issue, mapping), `{"Components":{"multi_select":[]}}`)
+}
+
+func TestPageMetadataLastSynced(t *testing.T) {
+	metadata := PageMetadata{
+		LastSyncedProperty: "Last Synced",
+		Now:                func() time.Time { return time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC) },
+	}
+
+	properties := map[string]interface{}{}
+	metadata.stampLastSynced(properties)
+	assertJSON(t, properties, `{"Last Synced":{"date":{"start":"2024-05-01T09:30:00Z"}}}`)
+
+	properties = map[string]interface{}{}
+	PageMetadata{}.stampLastSynced(properties)
+	assertJSON(t, properties, `{}`)
+}
+
+func TestBuildNotionPropertiesStoryPoints(t *testing.T) {
+	mapping := []FieldMapping{{JiraField: "story_points", NotionProperty: "Points", NotionType: NotionNumber}}
+
+	estimated := testIssue()
+	estimated.Fields.Custom = map[string]json.RawMessage{StoryPointsFieldID: json.RawMessage(`5.0`)}
+	assertJSON(t, mappedProperties(t, estimated, mapping), `{"Points":{"number":5}}`)
+
+	unestimated := testIssue()
+	unestimated.Fields.Custom = map[string]json.RawMessage{StoryPointsFieldID: json.RawMessage(`null`)}
+	assertJSON(t, mappedProperties(t, unestimated, mapping), `{}`)
+	assertJSON(t, mappedProperties(t, testIssue(), mapping), `{}`)
+}
+
+func TestBuildNotionPropertiesDueDate(t *testing.T) {
+	mapping := []FieldMapping{{JiraField: "duedate", NotionProperty: "Due", NotionType: NotionDate}}
+
+	var due, undated Issue
+	if err := json.Unmarshal([]byte(`{"key": "TU-1", "fields": {"duedate": "2024-06-30"}}`), &due); err != nil {
+		t.Fatalf("Error decoding issue: %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"key": "TU-2", "fields": {"duedate": null}}`), &undated); err != nil {
+		t.Fatalf("Error decoding issue: %v", err)
+	}
+
+	assertJSON(t, mappedProperties(t, due, mapping), `{"Due":{"date":{"start":"2024-06-30"}}}`)
+	assertJSON(t, mappedProperties(t, undated, mapping), `{}`)
+}
+
+func TestPageMetadataJiraURL(t *testing.T) {
+	properties := map[string]interface{}{}
+	PageMetadata{JiraWebBaseURL: "https://example.atlassian.net/"}.addJiraURL(properties, testIssue())
+	assertJSON(t, properties, `{"Jira URL":{"url":"https://example.atlassian.net/browse/TU-1"}}`)
+
+	properties = map[string]interface{}{}
+	PageMetadata{JiraURLProperty: "Link"}.addJiraURL(properties, testIssue())
+	assertJSON(t, properties, `{}`)
+}
+
+func TestAddIssueKeys(t *testing.T) {
+	notion := newTestNotionClient("")
+
+	unmapped := []FieldMapping{{JiraField: "summary", NotionProperty: "Name", NotionType: NotionTitle}}
+	properties := mappedProperties(t, testIssue(), unmapped)
+	notion.addIssueKeys(properties, testIssue(), unmapped)
+	assertJSON(t, properties, `{
+		"Jira Key": {"rich_text": [{"type": "text", "text": {"content": "TU-1"}}]},
+		"Name": {"title": [{"type": "text", "text": {"content": "Launch the onboarding bet"}}]}
+	}`)
+
+	asTitle := []FieldMapping{{JiraField: "key", NotionProperty: defaultJiraKeyProperty, NotionType: NotionTitle}}
+	properties = mappedProperties(t, testIssue(), asTitle)
+	notion.addIssueKeys(properties, testIssue(), asTitle)
+	assertJSON(t, properties, `{
+		"Jira Key": {"title": [{"type": "text", "text": {"content": "TU-1"}}]}
+	}`)
+
+	notion.JiraInstance = "alpha"
+	notion.JiraIDProperty = "Jira ID"
+	issue := testIssue()
+	issue.ID = "10001"
+	properties = mappedProperties(t, issue, asTitle)
+	notion.addIssueKeys(properties, issue, asTitle)
+	assertJSON(t, properties, `{
+		"Jira ID": {"rich_text": [{"type": "text", "text": {"content": "alpha:10001"}}]},
+		"Jira Key": {"title": [{"type": "text", "text": {"content": "alpha:TU-1"}}]}
+	}`)
+}
+
+func TestBuildNotionPropertiesValues(t *testing.T) {
+	mapping := []FieldMapping{
+		{JiraField: "status", NotionProperty: "Status", NotionType: NotionSelect, Values: map[string]string{"In Progress": "Doing"}},
+		{JiraField: "summary", NotionProperty: "Name", NotionType: NotionTitle, Values: map[string]string{"Something else": "Renamed"}},
+	}
+	assertJSON(t, mappedProperties(t, testIssue(), mapping), `{
+		"Name": {"title": [{"type": "text", "text": {"content": "Launch the onboarding bet"}}]},
+		"Status": {"select": {"name": "Doing"}}
+	}`)
+
+	strict := []FieldMapping{{JiraField: "status", NotionProperty: "Status", NotionType: NotionSelect, Values: map[string]string{"Done": "Shipped"}, StrictValues: true}}
+	if _, err := buildNotionProperties(testIssue(), strict, discardLogger); err == nil || !strings.Contains(err.Error(), `"In Progress"`) {
+		t.Errorf("Expected an error for the unmapped status, got %v", err)
+	}
+}
+
+func TestBuildNotionPropertiesSkipsUnknownFields(t *testing.T) {
+	mapping := []FieldMapping{
+		{JiraField: "summary", NotionProperty: "Name", NotionType: NotionTitle},
+		{JiraField: "customfield_99999", NotionProperty: "Mystery", NotionType: NotionRichText},
+		{JiraField: "status", NotionProperty: "Status", NotionType: "formula"},
+	}
+
+	var buf bytes.Buffer
+	properties, err := buildNotionProperties(testIssue(), mapping, slog.New(slog.NewTextHandler(&buf, nil)))
+	if err != nil {
+		t.Fatalf("Error building properties: %v", err)
+	}
+
+	if len(properties) != 1 {
+		t.Errorf("Expected %d property, got %d: %v", 1, len(properties), properties)
+	}
+
+	if _, ok := properties["Name"]; !ok {
+		t.Errorf("Expected Name property, got %v", properties)
+	}
+
+	logged := buf.String()
+	if strings.Count(logged, "level=WARN") != 2 || !strings.Contains(logged, "field=customfield_99999") || !strings.Contains(logged, "issueKey=TU-1") {
+		t.Errorf("Expected a warning for each skipped mapping, got %s", logged)
+	}
+}
+
+func TestResolveRelations(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Filter struct {
+				RichText struct {
+					Equals string `json:"equals"`
+				} `json:"rich_text"`
+			} `json:"filter"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding request: %v", err)
+		}
+
+		if body.Filter.RichText.Equals == "TU-1" {
+			w.Write([]byte(`{"results": [{"id": "parent-page"}]}`))
+			return
+		}
+		w.Write([]byte(`{"results": []}`))
+	}))
+	defer ts.Close()
+
+	mapping := []FieldMapping{{JiraField: "parent", NotionProperty: "Parent", NotionType: NotionRelation}}
+	notion := newTestNotionClient(ts.URL)
+
+	child := Issue{Key: "TU-2", Fields: IssueFields{Parent: &IssueParent{Key: "TU-1"}}}
+	properties, missing, err := resolveRelations(context.Background(), notion, "db-1", child, mapping)
+	if err != nil {
+		t.Fatalf("Error resolving relations: %v", err)
+	}
+	if len(missing) != 0 {
+		t.Errorf("Expected the relation to resolve, got %v missing", missing)
+	}
+	assertJSON(t, properties, `{"Parent": {"relation": [{"id": "parent-page"}]}}`)
+
+	orphan := Issue{Key: "TU-3", Fields: IssueFields{Parent: &IssueParent{Key: "TU-9"}}}
+	properties, missing, err = resolveRelations(context.Background(), notion, "db-1", orphan, mapping)
+	if err != nil {
+		t.Fatalf("Error resolving relations: %v", err)
+	}
+	if len(missing) != 1 || missing[0] != "TU-9" || len(properties) != 0 {
+		t.Errorf("Expected the unsynced parent TU-9 to be missing, got %v, %v", properties, missing)
+	}
+
+	if got := mappedProperties(t, child, mapping); len(got) != 0 {
+		t.Errorf("Expected buildNotionProperties to leave relations out, got %v", got)
+	}
+}
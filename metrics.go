@@ -0,0 +1,135 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+// Metrics counts the requests the sync makes to Jira and Notion. A nil
+// *Metrics records nothing, so clients built without one pay no cost.
+type Metrics struct {
+	jiraFetches     *prometheus.CounterVec
+	jiraFetchErrors *prometheus.CounterVec
+	jiraUpdates     *prometheus.CounterVec
+	notionWrites    *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+}
+
+// NewMetrics creates the sync's collectors and registers them with reg. A nil
+// reg returns a nil *Metrics, which disables instrumentation.
+func NewMetrics(reg prometheus.Registerer) (*Metrics, error) {
+	if reg == nil {
+		return nil, nil
+	}
+
+	m := &Metrics{
+		jiraFetches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "jira_fetch_total",
+			Help: "Jira issue searches, by result and final status code.",
+		}, []string{"result", "status"}),
+		jiraFetchErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "jira_fetch_errors_total",
+			Help: "Jira issue searches that failed, by final status code.",
+		}, []string{"status"}),
+		jiraUpdates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "jira_update_total",
+			Help: "Jira custom field updates, by result and status code.",
+		}, []string{"result", "status"}),
+		notionWrites: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "notion_write_total",
+			Help: "Notion page creates and updates, by result and status code.",
+		}, []string{"result", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "sync_request_duration_seconds",
+			Help:    "Latency of individual HTTP requests to Jira and Notion, by endpoint; its count is the number of requests.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"service", "method", "endpoint"}),
+	}
+
+	for _, collector := range []prometheus.Collector{m.jiraFetches, m.jiraFetchErrors, m.jiraUpdates, m.notionWrites, m.requestDuration} {
+		if err := reg.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// resultLabels returns the result and status label values for an operation
+// that ended with statusCode and err. A request that never got a response
+// has status "0".
+func resultLabels(statusCode int, err error) (string, string) {
+	result := resultSuccess
+	if err != nil {
+		result = resultError
+	}
+	return result, strconv.Itoa(statusCode)
+}
+
+func (m *Metrics) observeJiraFetch(statusCode int, err error) {
+	if m == nil {
+		return
+	}
+	result, status := resultLabels(statusCode, err)
+	m.jiraFetches.WithLabelValues(result, status).Inc()
+	if err != nil {
+		m.jiraFetchErrors.WithLabelValues(status).Inc()
+	}
+}
+
+func (m *Metrics) observeJiraUpdate(statusCode int, err error) {
+	if m == nil {
+		return
+	}
+	m.jiraUpdates.WithLabelValues(resultLabels(statusCode, err)).Inc()
+}
+
+func (m *Metrics) observeNotionWrite(statusCode int, err error) {
+	if m == nil {
+		return
+	}
+	m.notionWrites.WithLabelValues(resultLabels(statusCode, err)).Inc()
+}
+
+// observeRequest records the latency of one request to path, labelled with
+// its endpointPath.
+func (m *Metrics) observeRequest(service, method, path string, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.requestDuration.WithLabelValues(service, method, endpointPath(path)).Observe(time.Since(start).Seconds())
+}
+
+// notionIDPattern matches a Notion page, database or block id, with or
+// without dashes.
+var notionIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}$`)
+
+// endpointPath turns a request path into its pattern, so requests to the same
+// endpoint share a label however many issues they touch:
+// "/rest/api/2/issue/TU-1/transitions" becomes
+// "/rest/api/2/issue/{key}/transitions". Issue keys become {key}; numeric and
+// Notion ids become {id}.
+func endpointPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case issueKeyPattern.MatchString(segment):
+			segments[i] = "{key}"
+		// The number after /rest/api/ is the API version.
+		case segment != "" && strings.Trim(segment, "0123456789") == "" && (i == 0 || segments[i-1] != "api"):
+			segments[i] = "{id}"
+		case notionIDPattern.MatchString(segment):
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsCountRequests(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			w.Write([]byte(`{"total": 1, "issues": [{"key": "TU-1"}]}`))
+		case "PUT":
+			w.WriteHeader(http.StatusBadRequest)
+		case "POST":
+			w.Write([]byte(`{"id": "page-1"}`))
+		}
+	}))
+	defer ts.Close()
+
+	reg := prometheus.NewRegistry()
+	metrics, err := NewMetrics(reg)
+	if err != nil {
+		t.Fatalf("Error registering metrics: %v", err)
+	}
+
+	jira := newTestJiraClient(t, ts.URL)
+	jira.Metrics = metrics
+	notion := newTestNotionClient(ts.URL)
+	notion.Metrics = metrics
+
+	ctx := context.Background()
+	if _, err := jira.FetchIssues(ctx, ""); err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+	if _, _, err := jira.UpdateCustomField(ctx, "TU-1", "customfield_1", "value"); err == nil {
+		t.Fatal("Expected the update to fail")
+	}
+	if _, err := notion.CreatePage(ctx, "db-1", map[string]interface{}{}); err != nil {
+		t.Fatalf("Error creating page: %v", err)
+	}
+
+	if got := testutil.ToFloat64(metrics.jiraFetches.WithLabelValues(resultSuccess, "200")); got != 1 {
+		t.Errorf("Expected 1 successful fetch, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.jiraUpdates.WithLabelValues(resultError, "400")); got != 1 {
+		t.Errorf("Expected 1 failed update, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.notionWrites.WithLabelValues(resultSuccess, "200")); got != 1 {
+		t.Errorf("Expected 1 successful Notion write, got %v", got)
+	}
+	if got := testutil.CollectAndCount(metrics.requestDuration); got != 3 {
+		t.Errorf("Expected latency for 3 endpoints, got %d", got)
+	}
+}
+
+func TestEndpointPath(t *testing.T) {
+	tests := map[string]string{
+		"/rest/api/2/issue/TU-1":                               "/rest/api/2/issue/{key}",
+		"/rest/api/2/issue/TU-1/transitions":                   "/rest/api/2/issue/{key}/transitions",
+		"/rest/api/2/search":                                   "/rest/api/2/search",
+		"/rest/agile/1.0/sprint/42/issue":                      "/rest/agile/1.0/sprint/{id}/issue",
+		"/v1/pages/59833787-2cf9-4fdf-8782-e53db20768a5":       "/v1/pages/{id}",
+		"/v1/blocks/598337872cf94fdf8782e53db20768a5/children": "/v1/blocks/{id}/children",
+	}
+
+	for path, expected := range tests {
+		if got := endpointPath(path); got != expected {
+			t.Errorf("Expected %s to become %s, got %s", path, expected, got)
+		}
+	}
+}
+
+func TestNilMetricsIsNoOp(t *testing.T) {
+	metrics, err := NewMetrics(nil)
+	if err != nil || metrics != nil {
+		t.Fatalf("Expected nil metrics without a registry, got %v, %v", metrics, err)
+	}
+
+	metrics.observeJiraFetch(http.StatusOK, nil)
+	metrics.observeNotionWrite(http.StatusOK, nil)
+}
@@ -0,0 +1,605 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	notionBaseURL = "https://api.notion.com/v1"
+
+	// defaultNotionVersion is the Notion API version the client's requests
+	// and response parsing are written against. It is also the oldest one
+	// they are known to work with.
+	defaultNotionVersion = "2022-06-28"
+
+	// defaultNotionRateLimit is Notion's documented average of three
+	// requests per second per integration.
+	defaultNotionRateLimit = 3
+
+	// defaultJiraKeyProperty is the default NotionClient.JiraKeyProperty.
+	defaultJiraKeyProperty = "Jira Key"
+)
+
+// externalID is the JiraKeyProperty value of the page for issueKey when
+// syncing the Jira site instance; see NotionClient.JiraInstance.
+func externalID(instance, issueKey string) string {
+	if instance == "" {
+		return issueKey
+	}
+	return instance + ":" + issueKey
+}
+
+// issueKeyOf is the inverse of externalID. It is false for values of another
+// instance, whose pages this sync leaves alone.
+func issueKeyOf(instance, id string) (string, bool) {
+	if instance == "" {
+		return id, true
+	}
+	return strings.CutPrefix(id, instance+":")
+}
+
+// NotionClient writes pages into Notion databases using an integration token.
+type NotionClient struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+
+	// Logger receives dry-run records. A nil Logger discards them.
+	Logger *slog.Logger
+	// DryRun logs page writes instead of sending them. Queries still hit
+	// Notion.
+	DryRun bool
+	// MaxRetries is how many times a request is retried after a 429 or a
+	// transient 5xx. Zero disables retries.
+	MaxRetries int
+	// RetryBaseDelay caps the backoff before the first retry; the cap
+	// doubles on each subsequent attempt and the actual wait is a random
+	// fraction of it. Notion's Retry-After header takes precedence.
+	RetryBaseDelay time.Duration
+	// Rand draws the backoff jitter, as for JiraClient.Rand.
+	Rand *rand.Rand
+	// Limiter spaces out every request to stay under Notion's quota. A nil
+	// Limiter disables rate limiting.
+	Limiter *rate.Limiter
+	// MaxQueryResults caps how many pages QueryDatabase collects, to bound
+	// the cost of very large databases. Zero means no limit.
+	MaxQueryResults int
+	// Metrics counts page writes and request latency. A nil Metrics records
+	// nothing.
+	Metrics *Metrics
+	// VerifyWrites reads each created or updated page back and warns about
+	// properties Notion stored differently from what was sent, such as a
+	// value it coerced. It costs a request per write, so it is off by
+	// default.
+	VerifyWrites bool
+	// MaxResponseSize caps the bytes read from a response, as for
+	// JiraClient.MaxResponseSize.
+	MaxResponseSize int64
+	// APIVersion is sent as the Notion-Version header of every request, so
+	// Notion's changes only take effect once it is raised. A version older
+	// than defaultNotionVersion is sent as configured, with a warning.
+	APIVersion string
+	// JiraKeyProperty is the rich_text or title property holding each
+	// page's Jira key, by which FindPageByJiraKey finds the page again on
+	// later runs. The sync always writes it.
+	JiraKeyProperty string
+	// JiraIDProperty, when set, names a rich_text property that holds each
+	// page's Jira issue id. Pages are then found by id before key, so an
+	// issue moved to another project, which gets a new key, updates its page
+	// rather than creating a second one.
+	JiraIDProperty string
+	// JiraInstance, when set, names the Jira site being synced, so that
+	// syncs from several sites can share a database without their keys
+	// colliding. Pages are then keyed by "<instance>:<issue key>" rather than
+	// the bare key; see externalID. Each site syncs through its own client.
+	JiraInstance string
+
+	randMu          sync.Mutex
+	checkAPIVersion sync.Once
+}
+
+// NewNotionClient returns a client for the public Notion API. A nil
+// httpClient is replaced with one that times out after defaultHTTPTimeout.
+func NewNotionClient(token string, httpClient *http.Client) *NotionClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	return &NotionClient{
+		httpClient:     httpClient,
+		baseURL:        notionBaseURL,
+		token:          token,
+		MaxRetries:     defaultMaxRetries,
+		RetryBaseDelay: defaultRetryBaseDelay,
+		Limiter:        rate.NewLimiter(defaultNotionRateLimit, 1),
+
+		MaxResponseSize: defaultMaxResponseSize,
+		APIVersion:      defaultNotionVersion,
+		JiraKeyProperty: defaultJiraKeyProperty,
+	}
+}
+
+func (c *NotionClient) log() *slog.Logger {
+	if c.Logger == nil {
+		return discardLogger
+	}
+	return c.Logger
+}
+
+func (c *NotionClient) setHeaders(req *http.Request) {
+	c.checkAPIVersion.Do(func() {
+		// Versions are dates, so they sort as strings.
+		if c.APIVersion < defaultNotionVersion {
+			c.log().Warn("Notion API version is older than the one the sync is written for", "version", c.APIVersion, "expected", defaultNotionVersion)
+		}
+	})
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Notion-Version", c.APIVersion)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+}
+
+// do sends payload as JSON, or no body when it is nil, and decodes a
+// successful response into out, which may be nil. Rate-limited and transient
+// failures are retried like JiraClient.do. Non-2xx responses are returned as
+// errors carrying the body. The status code is 0 when no response was
+// received.
+func (c *NotionClient) do(ctx context.Context, method, url string, payload, out interface{}) (int, error) {
+	return c.doRetrying(ctx, method, url, payload, out, c.MaxRetries)
+}
+
+// doRetrying is do with an explicit retry budget, for requests that aren't
+// safe to resend blindly.
+func (c *NotionClient) doRetrying(ctx context.Context, method, url string, payload, out interface{}, maxRetries int) (int, error) {
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := waitForLimiter(ctx, c.Limiter); err != nil {
+			return 0, err
+		}
+
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, reader)
+		if err != nil {
+			return 0, err
+		}
+		c.setHeaders(req)
+
+		start := time.Now()
+		resp, err := c.httpClient.Do(req)
+		c.Metrics.observeRequest("notion", method, req.URL.Path, start)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			return 0, err
+		}
+
+		if isRetryableStatus(resp.StatusCode) && attempt < maxRetries {
+			delay, ok := retryAfter(resp)
+			if !ok {
+				delay = fullJitter(c.RetryBaseDelay<<attempt, c.Rand, &c.randMu)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return 0, ctx.Err()
+			case <-timer.C:
+			}
+			continue
+		}
+
+		return resp.StatusCode, decodeNotionResponse(resp, c.MaxResponseSize, out)
+	}
+}
+
+// decodeNotionResponse closes resp after decoding it into out, or returns an
+// error carrying the body for a non-2xx status.
+func decodeNotionResponse(resp *http.Response, maxSize int64, out interface{}) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return httpError(resp.StatusCode, readErrorBody(resp))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return decodeJSON(resp.Body, maxSize, out)
+}
+
+// write is do for requests that change Notion, honouring DryRun.
+func (c *NotionClient) write(ctx context.Context, method, url string, payload, out interface{}) error {
+	return c.writeRetrying(ctx, method, url, payload, out, c.MaxRetries)
+}
+
+// writeRetrying is write with an explicit retry budget; see doRetrying.
+func (c *NotionClient) writeRetrying(ctx context.Context, method, url string, payload, out interface{}, maxRetries int) error {
+	if c.DryRun {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		logDryRun(c.log(), method, url, body)
+		return nil
+	}
+
+	statusCode, err := c.doRetrying(ctx, method, url, payload, out, maxRetries)
+	c.Metrics.observeNotionWrite(statusCode, err)
+	return err
+}
+
+// NotionPage is a page returned from the Notion API. Properties are left
+// raw because their shape depends on each property's type.
+type NotionPage struct {
+	ID             string                     `json:"id"`
+	LastEditedTime time.Time                  `json:"last_edited_time"`
+	Properties     map[string]json.RawMessage `json:"properties"`
+}
+
+// PlainText returns the concatenated text of a title or rich_text property.
+func (p NotionPage) PlainText(property string) string {
+	var value struct {
+		Title    []notionPlainText `json:"title"`
+		RichText []notionPlainText `json:"rich_text"`
+	}
+	if err := json.Unmarshal(p.Properties[property], &value); err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, part := range append(value.Title, value.RichText...) {
+		b.WriteString(part.PlainText)
+	}
+	return b.String()
+}
+
+// SelectName returns the chosen option of a select or status property, or ""
+// when nothing is selected.
+func (p NotionPage) SelectName(property string) string {
+	var value struct {
+		Select *struct {
+			Name string `json:"name"`
+		} `json:"select"`
+		Status *struct {
+			Name string `json:"name"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(p.Properties[property], &value); err != nil {
+		return ""
+	}
+
+	switch {
+	case value.Select != nil:
+		return value.Select.Name
+	case value.Status != nil:
+		return value.Status.Name
+	}
+	return ""
+}
+
+// Values parses the page's properties into plain Go values by type: text for
+// title, rich_text, select, status and url, []string for multi_select, a
+// time.Time for date's start and a float64 for number. Empty select, date and
+// number properties are nil. Properties of other types are left out.
+func (p NotionPage) Values() map[string]interface{} {
+	values := make(map[string]interface{}, len(p.Properties))
+	for name, raw := range p.Properties {
+		var property struct {
+			Type        string `json:"type"`
+			MultiSelect []struct {
+				Name string `json:"name"`
+			} `json:"multi_select"`
+			Date *struct {
+				Start string `json:"start"`
+			} `json:"date"`
+			Number *float64 `json:"number"`
+			URL    *string  `json:"url"`
+		}
+		if err := json.Unmarshal(raw, &property); err != nil {
+			continue
+		}
+
+		switch property.Type {
+		case NotionTitle, NotionRichText:
+			values[name] = p.PlainText(name)
+		case NotionSelect, "status":
+			if option := p.SelectName(name); option != "" {
+				values[name] = option
+			} else {
+				values[name] = nil
+			}
+		case NotionMultiSelect:
+			options := []string{}
+			for _, option := range property.MultiSelect {
+				options = append(options, option.Name)
+			}
+			values[name] = options
+		case NotionDate:
+			values[name] = nil
+			if property.Date != nil {
+				if t, err := parseNotionDate(property.Date.Start); err == nil {
+					values[name] = t
+				}
+			}
+		case NotionNumber:
+			values[name] = nil
+			if property.Number != nil {
+				values[name] = *property.Number
+			}
+		case NotionURL:
+			values[name] = ""
+			if property.URL != nil {
+				values[name] = *property.URL
+			}
+		}
+	}
+	return values
+}
+
+// parseNotionDate parses a date property's start, which Notion gives with a
+// time and offset or as a bare date.
+func parseNotionDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+type notionPlainText struct {
+	PlainText string `json:"plain_text"`
+}
+
+type notionQueryResponse struct {
+	Results    []NotionPage `json:"results"`
+	HasMore    bool         `json:"has_more"`
+	NextCursor string       `json:"next_cursor"`
+}
+
+// CreatePage adds a page with the given property values to a database and
+// returns the new page's id. An optional PageOptions sets its icon and cover.
+func (c *NotionClient) CreatePage(ctx context.Context, databaseID string, properties map[string]interface{}, opts ...PageOptions) (string, error) {
+	return c.createPage(ctx, databaseID, properties, pageOptions(opts), c.MaxRetries)
+}
+
+func (c *NotionClient) createPage(ctx context.Context, databaseID string, properties map[string]interface{}, page PageOptions, maxRetries int) (string, error) {
+	payload := map[string]interface{}{
+		"parent":     map[string]interface{}{"database_id": databaseID},
+		"properties": properties,
+	}
+	page.apply(payload)
+
+	var created NotionPage
+	if err := c.writeRetrying(ctx, "POST", c.baseURL+"/pages", payload, &created, maxRetries); err != nil {
+		return "", err
+	}
+
+	c.verifyWrite(ctx, created.ID, properties)
+	return created.ID, nil
+}
+
+// UpdatePage overwrites the given property values on an existing page. An
+// optional PageOptions replaces its icon and cover; without one they are
+// left as they are.
+func (c *NotionClient) UpdatePage(ctx context.Context, pageID string, properties map[string]interface{}, opts ...PageOptions) error {
+	payload := map[string]interface{}{"properties": properties}
+	pageOptions(opts).apply(payload)
+
+	if err := c.write(ctx, "PATCH", c.baseURL+"/pages/"+pageID, payload, nil); err != nil {
+		return err
+	}
+
+	c.verifyWrite(ctx, pageID, properties)
+	return nil
+}
+
+// GetPage returns the current property values of a page, parsed as by
+// NotionPage.Values.
+func (c *NotionClient) GetPage(ctx context.Context, pageID string) (map[string]interface{}, error) {
+	var page NotionPage
+	if _, err := c.do(ctx, "GET", c.baseURL+"/pages/"+pageID, nil, &page); err != nil {
+		return nil, err
+	}
+	return page.Values(), nil
+}
+
+// ArchivePage moves a page to Notion's trash, which can be undone from the
+// Notion UI.
+func (c *NotionClient) ArchivePage(ctx context.Context, pageID string) error {
+	payload := map[string]interface{}{"archived": true}
+
+	return c.write(ctx, "PATCH", c.baseURL+"/pages/"+pageID, payload, nil)
+}
+
+// maxBlocksPerAppend is the most children Notion accepts in one append.
+const maxBlocksPerAppend = 100
+
+// AppendBlocks adds blocks to the end of a page's content, splitting them
+// into requests of at most maxBlocksPerAppend.
+func (c *NotionClient) AppendBlocks(ctx context.Context, pageID string, blocks []NotionBlock) error {
+	for start := 0; start < len(blocks); start += maxBlocksPerAppend {
+		end := min(start+maxBlocksPerAppend, len(blocks))
+		payload := map[string]interface{}{"children": blocks[start:end]}
+
+		if err := c.write(ctx, "PATCH", c.baseURL+"/blocks/"+pageID+"/children", payload, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendDescription converts an issue's description to blocks and adds them
+// to the end of a page's content. Long descriptions are split across
+// requests by AppendBlocks, in order.
+func (c *NotionClient) AppendDescription(ctx context.Context, pageID string, issue Issue) error {
+	return c.AppendBlocks(ctx, pageID, adfToNotionBlocks(issue.Fields.Description))
+}
+
+// notionMaxPageSize is the most results Notion returns per query page.
+const notionMaxPageSize = 100
+
+// QueryDatabase returns every page in a database matching filter, following
+// Notion's next_cursor until has_more is false or MaxQueryResults pages have
+// been collected. A nil filter matches all pages.
+func (c *NotionClient) QueryDatabase(ctx context.Context, databaseID string, filter interface{}) ([]NotionPage, error) {
+	collected := 0
+	pages, err := collectAll(func(cursor string) ([]NotionPage, string, error) {
+		pageSize := notionMaxPageSize
+		if c.MaxQueryResults > 0 {
+			pageSize = min(pageSize, c.MaxQueryResults-collected)
+		}
+
+		payload := map[string]interface{}{"page_size": pageSize}
+		if filter != nil {
+			payload["filter"] = filter
+		}
+		if cursor != "" {
+			payload["start_cursor"] = cursor
+		}
+
+		var resp notionQueryResponse
+		if _, err := c.do(ctx, "POST", c.baseURL+"/databases/"+databaseID+"/query", payload, &resp); err != nil {
+			return nil, "", err
+		}
+		collected += len(resp.Results)
+
+		if c.MaxQueryResults > 0 && collected >= c.MaxQueryResults {
+			if resp.HasMore {
+				c.log().Warn("database query truncated", "databaseID", databaseID, "maxResults", c.MaxQueryResults)
+			}
+			return resp.Results, "", nil
+		}
+		if !resp.HasMore {
+			return resp.Results, "", nil
+		}
+		return resp.Results, resp.NextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if c.MaxQueryResults > 0 && len(pages) > c.MaxQueryResults {
+		pages = pages[:c.MaxQueryResults]
+	}
+	return pages, nil
+}
+
+// FindOrCreatePage returns the page for the issue with jiraKey and, if
+// known, jiraID in databaseID, found as by FindIssuePage, creating it
+// with properties if there is none, and reports whether this call created
+// it. Notion has no idempotency keys, so a create that fails transiently,
+// which may still have gone through, is never resent blindly: the page is
+// looked up again first. Because the lookup precedes every create, a run that
+// crashed before saving its SyncState finds the page rather than duplicating
+// it.
+func (c *NotionClient) FindOrCreatePage(ctx context.Context, databaseID, jiraKey, jiraID string, properties map[string]interface{}, opts ...PageOptions) (pageID string, created bool, err error) {
+	attempted := false
+	for attempt := 0; ; attempt++ {
+		pageID, err := c.FindIssuePage(ctx, databaseID, jiraKey, jiraID)
+		if err != nil {
+			return "", false, err
+		}
+		if pageID != "" {
+			// Found after a failed create, the page is the one that create
+			// made, with properties already written.
+			return pageID, attempted, nil
+		}
+
+		pageID, err = c.createPage(ctx, databaseID, properties, pageOptions(opts), 0)
+		if err == nil {
+			return pageID, true, nil
+		}
+		if !retryableCreate(ctx, err) || attempt >= c.MaxRetries {
+			return "", false, err
+		}
+		attempted = true
+
+		c.log().Warn("creating Notion page failed; checking whether it was created before retrying", "jiraKey", jiraKey, "error", err)
+		timer := time.NewTimer(fullJitter(c.RetryBaseDelay<<attempt, c.Rand, &c.randMu))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryableCreate reports whether a failed create may have reached Notion and
+// is worth retrying: a transient status, or no response at all.
+func retryableCreate(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Retryable()
+	}
+	return true
+}
+
+// FindPageByJiraKey returns the id of the page whose JiraKeyProperty equals
+// the externalID of jiraKey, or "" if the database has no such page. Notion's
+// rich_text filter also matches a title property.
+func (c *NotionClient) FindPageByJiraKey(ctx context.Context, databaseID, jiraKey string) (string, error) {
+	return c.findPageBy(ctx, databaseID, c.JiraKeyProperty, externalID(c.JiraInstance, jiraKey))
+}
+
+// FindIssuePage returns the id of the page for an issue, or "" if there is
+// none. With JiraIDProperty set and jiraID known, the page holding the id is
+// preferred, since the key may have changed since the page was written.
+func (c *NotionClient) FindIssuePage(ctx context.Context, databaseID, jiraKey, jiraID string) (string, error) {
+	if c.JiraIDProperty != "" && jiraID != "" {
+		pageID, err := c.findPageBy(ctx, databaseID, c.JiraIDProperty, externalID(c.JiraInstance, jiraID))
+		if err != nil || pageID != "" {
+			return pageID, err
+		}
+	}
+	return c.FindPageByJiraKey(ctx, databaseID, jiraKey)
+}
+
+// findPageBy returns the id of a page whose text property equals value.
+func (c *NotionClient) findPageBy(ctx context.Context, databaseID, property, value string) (string, error) {
+	filter := map[string]interface{}{
+		"property":  property,
+		"rich_text": map[string]interface{}{"equals": value},
+	}
+
+	pages, err := c.QueryDatabase(ctx, databaseID, filter)
+	if err != nil {
+		return "", err
+	}
+
+	if len(pages) == 0 {
+		return "", nil
+	}
+	return pages[0].ID, nil
+}
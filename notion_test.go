@@ -0,0 +1,479 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestNotionClient(url string) *NotionClient {
+	client := NewNotionClient("notionToken", nil)
+	client.baseURL = url
+	client.Limiter = nil
+	return client
+}
+
+func TestCreatePage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/pages" {
+			t.Errorf("Expected POST /pages, got %s %s", r.Method, r.URL.Path)
+		}
+
+		if got := r.Header.Get("Authorization"); got != "Bearer notionToken" {
+			t.Errorf("Expected Authorization header to be %s, got %s", "Bearer notionToken", got)
+		}
+
+		if got := r.Header.Get("Notion-Version"); got != defaultNotionVersion {
+			t.Errorf("Expected Notion-Version header to be %s, got %s", defaultNotionVersion, got)
+		}
+
+		var body struct {
+			Parent struct {
+				DatabaseID string `json:"database_id"`
+			} `json:"parent"`
+			Properties map[string]interface{} `json:"properties"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding request: %v", err)
+		}
+
+		if body.Parent.DatabaseID != "db-1" {
+			t.Errorf("Expected database id to be %s, got %s", "db-1", body.Parent.DatabaseID)
+		}
+
+		if _, ok := body.Properties["Name"]; !ok {
+			t.Errorf("Expected Name property in request, got %v", body.Properties)
+		}
+
+		err := json.NewEncoder(w).Encode(map[string]string{"id": "page-1"})
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+
+	defer ts.Close()
+
+	properties := map[string]interface{}{
+		"Name": map[string]interface{}{"title": []interface{}{map[string]interface{}{"text": map[string]string{"content": "TU-1"}}}},
+	}
+
+	pageID, err := newTestNotionClient(ts.URL).CreatePage(context.Background(), "db-1", properties)
+	if err != nil {
+		t.Fatalf("Error creating page: %v", err)
+	}
+
+	if pageID != "page-1" {
+		t.Errorf("Expected page id to be %s, got %s", "page-1", pageID)
+	}
+}
+
+func TestNotionAPIVersion(t *testing.T) {
+	server := newNotionTestServer(t)
+	notion := server.NotionClient()
+	notion.APIVersion = "2025-09-03"
+
+	if _, err := notion.CreatePage(context.Background(), "db-1", map[string]interface{}{}); err != nil {
+		t.Fatalf("Error creating page: %v", err)
+	}
+	if _, err := notion.QueryDatabase(context.Background(), "db-1", nil); err != nil {
+		t.Fatalf("Error querying database: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("Expected a create and a query, got %+v", requests)
+	}
+	for _, request := range requests {
+		if got := request.Header.Get("Notion-Version"); got != "2025-09-03" {
+			t.Errorf("Expected %s %s to send Notion-Version 2025-09-03, got %q", request.Method, request.Path, got)
+		}
+	}
+}
+
+func TestNotionAPIVersionWarnsOnceWhenOld(t *testing.T) {
+	server := newNotionTestServer(t)
+	var logs bytes.Buffer
+	notion := server.NotionClient()
+	notion.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	notion.APIVersion = "2021-05-13"
+
+	for i := 0; i < 2; i++ {
+		if _, err := notion.QueryDatabase(context.Background(), "db-1", nil); err != nil {
+			t.Fatalf("Error querying database: %v", err)
+		}
+	}
+
+	if got := strings.Count(logs.String(), "older than"); got != 1 {
+		t.Errorf("Expected one warning about the old version, got %d in %q", got, logs.String())
+	}
+	if request, _ := server.LastRequest("POST", "/databases/db-1/query"); request.Header.Get("Notion-Version") != "2021-05-13" {
+		t.Errorf("Expected the configured version to be sent anyway")
+	}
+}
+
+func TestUpdatePage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PATCH" || r.URL.Path != "/pages/page-1" {
+			t.Errorf("Expected PATCH /pages/page-1, got %s %s", r.Method, r.URL.Path)
+		}
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding request: %v", err)
+		}
+
+		if _, ok := body["properties"]; !ok {
+			t.Errorf("Expected properties in request, got %v", body)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	defer ts.Close()
+
+	err := newTestNotionClient(ts.URL).UpdatePage(context.Background(), "page-1", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Error updating page: %v", err)
+	}
+}
+
+func TestGetPage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/pages/page-1" {
+			t.Errorf("Expected GET /pages/page-1, got %s %s", r.Method, r.URL.Path)
+		}
+
+		_, _ = w.Write([]byte(`{"id": "page-1", "properties": {
+			"Name": {"type": "title", "title": [{"plain_text": "Bet "}, {"plain_text": "one"}]},
+			"Status": {"type": "select", "select": {"name": "In Progress"}},
+			"Priority": {"type": "select", "select": null},
+			"Updated": {"type": "date", "date": {"start": "2024-05-01T09:30:00.000+01:00"}},
+			"Due": {"type": "date", "date": {"start": "2024-06-01"}},
+			"Story Points": {"type": "number", "number": 5}
+		}}`))
+	}))
+
+	defer ts.Close()
+
+	values, err := newTestNotionClient(ts.URL).GetPage(context.Background(), "page-1")
+	if err != nil {
+		t.Fatalf("Error getting page: %v", err)
+	}
+
+	if values["Name"] != "Bet one" {
+		t.Errorf("Expected title to be Bet one, got %v", values["Name"])
+	}
+
+	if values["Status"] != "In Progress" {
+		t.Errorf("Expected status to be In Progress, got %v", values["Status"])
+	}
+
+	if v, ok := values["Priority"]; !ok || v != nil {
+		t.Errorf("Expected an empty select to be nil, got %v", v)
+	}
+
+	updated, ok := values["Updated"].(time.Time)
+	if !ok || !updated.Equal(time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("Expected updated to be 2024-05-01 08:30 UTC, got %v", values["Updated"])
+	}
+
+	due, ok := values["Due"].(time.Time)
+	if !ok || !due.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected due to be 2024-06-01, got %v", values["Due"])
+	}
+
+	if values["Story Points"] != 5.0 {
+		t.Errorf("Expected story points to be 5, got %v", values["Story Points"])
+	}
+}
+
+func TestNotionErrorHandling(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, err := w.Write([]byte(`{"code":"validation_error"}`))
+		if err != nil {
+			t.Fatalf("Error writing response: %v", err)
+		}
+	}))
+
+	defer ts.Close()
+
+	_, err := newTestNotionClient(ts.URL).CreatePage(context.Background(), "db-1", map[string]interface{}{})
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+
+	expectedError := `HTTP error! Status: 400, Body: {"code":"validation_error"}`
+	if got := err.Error(); got != expectedError {
+		t.Errorf("Expected %q, got %q", expectedError, got)
+	}
+}
+
+func TestFindPageByJiraKey(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/databases/db-1/query" {
+			t.Errorf("Expected POST /databases/db-1/query, got %s %s", r.Method, r.URL.Path)
+		}
+
+		var body struct {
+			Filter struct {
+				Property string `json:"property"`
+				RichText struct {
+					Equals string `json:"equals"`
+				} `json:"rich_text"`
+			} `json:"filter"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding request: %v", err)
+		}
+
+		if body.Filter.Property != defaultJiraKeyProperty || body.Filter.RichText.Equals != "TU-1" {
+			t.Errorf("Expected filter on %s equals %s, got %+v", defaultJiraKeyProperty, "TU-1", body.Filter)
+		}
+
+		err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"results":  []map[string]string{{"id": "page-1"}},
+			"has_more": false,
+		})
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+
+	defer ts.Close()
+
+	pageID, err := newTestNotionClient(ts.URL).FindPageByJiraKey(context.Background(), "db-1", "TU-1")
+	if err != nil {
+		t.Fatalf("Error finding page: %v", err)
+	}
+
+	if pageID != "page-1" {
+		t.Errorf("Expected page id to be %s, got %s", "page-1", pageID)
+	}
+}
+
+func TestFindPageByJiraKeyNoMatch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := json.NewEncoder(w).Encode(map[string]interface{}{"results": []interface{}{}, "has_more": false})
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+
+	defer ts.Close()
+
+	pageID, err := newTestNotionClient(ts.URL).FindPageByJiraKey(context.Background(), "db-1", "TU-404")
+	if err != nil {
+		t.Fatalf("Error finding page: %v", err)
+	}
+
+	if pageID != "" {
+		t.Errorf("Expected no page id, got %s", pageID)
+	}
+}
+
+func TestQueryDatabaseFollowsCursor(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding request: %v", err)
+		}
+
+		response := map[string]interface{}{"results": []map[string]string{{"id": "page-1"}}, "has_more": true, "next_cursor": "cursor-2"}
+		if body["start_cursor"] == "cursor-2" {
+			response = map[string]interface{}{"results": []map[string]string{{"id": "page-2"}}, "has_more": false}
+		}
+
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+
+	defer ts.Close()
+
+	pages, err := newTestNotionClient(ts.URL).QueryDatabase(context.Background(), "db-1", nil)
+	if err != nil {
+		t.Fatalf("Error querying database: %v", err)
+	}
+
+	if len(pages) != 2 || pages[0].ID != "page-1" || pages[1].ID != "page-2" {
+		t.Errorf("Expected pages page-1 and page-2, got %+v", pages)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected %d requests, got %d", 2, requests)
+	}
+}
+
+func TestQueryDatabaseMaxResults(t *testing.T) {
+	var pageSizes []float64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding request: %v", err)
+		}
+		pageSizes = append(pageSizes, body["page_size"].(float64))
+
+		response := map[string]interface{}{"results": []map[string]string{{"id": "page-1"}, {"id": "page-2"}}, "has_more": true, "next_cursor": "cursor-2"}
+		if body["start_cursor"] == "cursor-2" {
+			response["results"] = []map[string]string{{"id": "page-3"}, {"id": "page-4"}}
+		}
+
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+
+	defer ts.Close()
+
+	client := newTestNotionClient(ts.URL)
+	client.MaxQueryResults = 3
+
+	pages, err := client.QueryDatabase(context.Background(), "db-1", nil)
+	if err != nil {
+		t.Fatalf("Error querying database: %v", err)
+	}
+
+	if len(pages) != 3 || pages[2].ID != "page-3" {
+		t.Errorf("Expected the first 3 pages, got %+v", pages)
+	}
+
+	if len(pageSizes) != 2 || pageSizes[0] != 3 || pageSizes[1] != 1 {
+		t.Errorf("Expected page sizes [3 1], got %v", pageSizes)
+	}
+}
+
+func TestNotionDryRun(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	client := newTestNotionClient(ts.URL)
+	client.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+	client.DryRun = true
+
+	if _, err := client.CreatePage(context.Background(), "db-1", map[string]interface{}{}); err != nil {
+		t.Fatalf("Error creating page: %v", err)
+	}
+
+	if err := client.UpdatePage(context.Background(), "page-1", map[string]interface{}{}); err != nil {
+		t.Fatalf("Error updating page: %v", err)
+	}
+
+	if requests != 0 {
+		t.Errorf("Expected no requests in dry run, got %d", requests)
+	}
+
+	if !strings.Contains(buf.String(), "method=POST") || !strings.Contains(buf.String(), "method=PATCH") {
+		t.Errorf("Expected dry run log to contain both writes, got %s", buf.String())
+	}
+}
+
+func TestNotionRetriesRateLimitedRequests(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"id": "page-1"}`))
+	}))
+	defer ts.Close()
+
+	pageID, err := newTestNotionClient(ts.URL).CreatePage(context.Background(), "db-1", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Error creating page: %v", err)
+	}
+
+	if pageID != "page-1" {
+		t.Errorf("Expected page id %s, got %s", "page-1", pageID)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
+
+func TestNewNotionClientDefaultRateLimit(t *testing.T) {
+	client := NewNotionClient("notionToken", nil)
+
+	if client.Limiter == nil || client.Limiter.Limit() != defaultNotionRateLimit {
+		t.Errorf("Expected a %d rps limiter, got %v", defaultNotionRateLimit, client.Limiter)
+	}
+}
+
+func TestAppendBlocksChunks(t *testing.T) {
+	var chunks [][]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Children []struct {
+				Paragraph struct {
+					RichText []struct {
+						Text struct {
+							Content string `json:"content"`
+						} `json:"text"`
+					} `json:"rich_text"`
+				} `json:"paragraph"`
+			} `json:"children"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding request: %v", err)
+		}
+
+		var chunk []string
+		for _, child := range body.Children {
+			chunk = append(chunk, child.Paragraph.RichText[0].Text.Content)
+		}
+		chunks = append(chunks, chunk)
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	var blocks []NotionBlock
+	for i := 0; i < 250; i++ {
+		blocks = append(blocks, plainTextBlocks(strconv.Itoa(i))...)
+	}
+
+	if err := newTestNotionClient(ts.URL).AppendBlocks(context.Background(), "page-1", blocks); err != nil {
+		t.Fatalf("Error appending blocks: %v", err)
+	}
+
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 append requests, got %d", len(chunks))
+	}
+
+	for i, size := range []int{100, 100, 50} {
+		if len(chunks[i]) != size {
+			t.Errorf("Expected request %d to carry %d blocks, got %d", i, size, len(chunks[i]))
+		}
+	}
+
+	next := 0
+	for _, chunk := range chunks {
+		for _, content := range chunk {
+			if content != strconv.Itoa(next) {
+				t.Fatalf("Expected block %d next, got %s", next, content)
+			}
+			next++
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// notionTestServer is a fake Notion API for testing the Notion path without
+// credentials. It records every request it receives and answers page creates,
+// page updates and database queries with the configured responses, or with
+// an empty success when none is set.
+type notionTestServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []notionRequest
+	creates  int
+
+	// Create, Update, Query and Get answer POST /pages, PATCH /pages/{id},
+	// POST /databases/{id}/query and GET /pages/{id}.
+	Create, Update, Query, Get *notionResponse
+}
+
+// notionResponse is a canned reply. A zero Status means 200.
+type notionResponse struct {
+	Status int
+	Body   string
+}
+
+// notionRequest is one request the server received, with its headers and
+// JSON body.
+type notionRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// newNotionTestServer starts a notionTestServer, closed when the test ends.
+func newNotionTestServer(t *testing.T) *notionTestServer {
+	t.Helper()
+
+	s := &notionTestServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.serve(t, w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// NotionClient returns a client that talks to the server.
+func (s *notionTestServer) NotionClient() *NotionClient {
+	return newTestNotionClient(s.URL)
+}
+
+// Requests returns the requests received so far, in order.
+func (s *notionTestServer) Requests() []notionRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]notionRequest(nil), s.requests...)
+}
+
+// LastRequest returns the latest request for method and path.
+func (s *notionTestServer) LastRequest(method, path string) (notionRequest, bool) {
+	requests := s.Requests()
+	for i := len(requests) - 1; i >= 0; i-- {
+		if requests[i].Method == method && requests[i].Path == path {
+			return requests[i], true
+		}
+	}
+	return notionRequest{}, false
+}
+
+func (s *notionTestServer) serve(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Errorf("Error reading request: %v", err)
+	}
+	request := notionRequest{Method: r.Method, Path: r.URL.Path, Header: r.Header}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &request.Body); err != nil {
+			t.Errorf("Error decoding %s %s: %v", r.Method, r.URL.Path, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	var response *notionResponse
+	var fallback string
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/pages":
+		s.creates++
+		response, fallback = s.Create, `{"id": "page-`+strconv.Itoa(s.creates)+`"}`
+	case r.Method == "PATCH" && strings.HasPrefix(r.URL.Path, "/pages/"):
+		response, fallback = s.Update, `{"id": "`+strings.TrimPrefix(r.URL.Path, "/pages/")+`"}`
+	case r.Method == "POST" && strings.HasPrefix(r.URL.Path, "/databases/") && strings.HasSuffix(r.URL.Path, "/query"):
+		response, fallback = s.Query, `{"results": [], "has_more": false}`
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/pages/"):
+		response, fallback = s.Get, `{"id": "`+strings.TrimPrefix(r.URL.Path, "/pages/")+`", "properties": {}}`
+	default:
+		response = &notionResponse{Status: http.StatusNotFound, Body: `{"object": "error", "code": "object_not_found"}`}
+	}
+	s.mu.Unlock()
+
+	if response == nil {
+		response = &notionResponse{Body: fallback}
+	}
+	if response.Status != 0 {
+		w.WriteHeader(response.Status)
+	}
+	_, _ = w.Write([]byte(response.Body))
+}
+
+func TestNotionTestServerRecordsCreatePage(t *testing.T) {
+	server := newNotionTestServer(t)
+
+	properties := buildNotionPropertiesOrFail(t, testIssue(), defaultFieldMapping)
+	pageID, err := server.NotionClient().CreatePage(context.Background(), "db-1", properties)
+	if err != nil {
+		t.Fatalf("Error creating page: %v", err)
+	}
+	if pageID != "page-1" {
+		t.Errorf("Expected page-1, got %s", pageID)
+	}
+
+	request, ok := server.LastRequest("POST", "/pages")
+	if !ok {
+		t.Fatalf("Expected a create request, got %+v", server.Requests())
+	}
+	if parent, _ := request.Body["parent"].(map[string]interface{}); parent["database_id"] != "db-1" {
+		t.Errorf("Expected the page to be created in db-1, got %v", request.Body["parent"])
+	}
+	written, _ := request.Body["properties"].(map[string]interface{})
+	assertJSON(t, written["Name"], `{"title": [{"type": "text", "text": {"content": "Launch the onboarding bet"}}]}`)
+	assertJSON(t, written["Status"], `{"select": {"name": "In Progress"}}`)
+}
+
+func TestNotionTestServerConfiguredResponses(t *testing.T) {
+	server := newNotionTestServer(t)
+	server.Query = &notionResponse{Body: `{"results": [{"id": "page-7"}], "has_more": false}`}
+	server.Update = &notionResponse{Status: http.StatusBadRequest, Body: `{"code": "validation_error"}`}
+	notion := server.NotionClient()
+
+	pageID, err := notion.FindPageByJiraKey(context.Background(), "db-1", "TU-7")
+	if err != nil || pageID != "page-7" {
+		t.Fatalf("Expected the configured page-7, got %q, %v", pageID, err)
+	}
+	if err := notion.UpdatePage(context.Background(), pageID, map[string]interface{}{}); err == nil {
+		t.Errorf("Expected the configured 400 to fail the update")
+	}
+
+	query, _ := server.LastRequest("POST", "/databases/db-1/query")
+	assertJSON(t, query.Body["filter"], `{"property": "Jira Key", "rich_text": {"equals": "TU-7"}}`)
+}
+
+func buildNotionPropertiesOrFail(t *testing.T, issue Issue, mapping []FieldMapping) map[string]interface{} {
+	t.Helper()
+
+	properties, err := buildNotionProperties(issue, mapping, discardLogger)
+	if err != nil {
+		t.Fatalf("Error building properties: %v", err)
+	}
+	return properties
+}
@@ -0,0 +1,25 @@
+package main
+
+// collectAll gathers every item of a paged listing. fetchPage is called with
+// "" for the first page and then with each cursor it returns, until it
+// returns an empty cursor or repeats one, which guards against servers that
+// hand back the same page forever. On error the items collected so far are
+// returned alongside it.
+func collectAll[T any](fetchPage func(cursor string) (items []T, next string, err error)) ([]T, error) {
+	all := []T{}
+	seen := map[string]bool{}
+	cursor := ""
+	for {
+		items, next, err := fetchPage(cursor)
+		if err != nil {
+			return all, err
+		}
+		all = append(all, items...)
+
+		if next == "" || seen[next] {
+			return all, nil
+		}
+		seen[next] = true
+		cursor = next
+	}
+}
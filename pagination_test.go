@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCollectAll(t *testing.T) {
+	pages := map[string]struct {
+		items []string
+		next  string
+	}{
+		"":   {[]string{"a", "b"}, "p2"},
+		"p2": {[]string{"c"}, "p3"},
+		"p3": {[]string{"d"}, ""},
+	}
+
+	var cursors []string
+	items, err := collectAll(func(cursor string) ([]string, string, error) {
+		cursors = append(cursors, cursor)
+		page := pages[cursor]
+		return page.items, page.next, nil
+	})
+	if err != nil {
+		t.Fatalf("Error collecting pages: %v", err)
+	}
+
+	if got := strings.Join(items, ","); got != "a,b,c,d" {
+		t.Errorf("Expected items a,b,c,d, got %s", got)
+	}
+	if got := strings.Join(cursors, ","); got != ",p2,p3" {
+		t.Errorf("Expected cursors ,p2,p3, got %s", got)
+	}
+}
+
+func TestCollectAllError(t *testing.T) {
+	failure := errors.New("page two failed")
+	calls := 0
+	items, err := collectAll(func(cursor string) ([]string, string, error) {
+		calls++
+		if cursor == "p2" {
+			return nil, "", failure
+		}
+		return []string{"a"}, "p2", nil
+	})
+
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected the page error, got %v", err)
+	}
+	if calls != 2 || len(items) != 1 {
+		t.Errorf("Expected to stop after page two with the first page's items, got %d calls and %v", calls, items)
+	}
+}
+
+func TestCollectAllRepeatedCursor(t *testing.T) {
+	calls := 0
+	items, err := collectAll(func(cursor string) ([]int, string, error) {
+		calls++
+		return []int{calls}, "same", nil
+	})
+	if err != nil {
+		t.Fatalf("Error collecting pages: %v", err)
+	}
+
+	if calls != 2 || len(items) != 2 {
+		t.Errorf("Expected a repeated cursor to stop paging after 2 pages, got %d calls", calls)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitStatus is Jira's quota as of the last response that reported it
+// through the X-RateLimit-Remaining and X-RateLimit-Reset headers.
+type RateLimitStatus struct {
+	// Known is false until a response has carried the headers.
+	Known     bool
+	Remaining int
+	// Reset is when the quota refills. It is zero if Jira didn't say.
+	Reset time.Time
+}
+
+// RateLimit returns the quota Jira last reported.
+func (c *JiraClient) RateLimit() RateLimitStatus {
+	c.rateLimitMu.Lock()
+	defer c.rateLimitMu.Unlock()
+	return c.rateLimit
+}
+
+// recordRateLimit keeps the quota reported by resp, if any.
+func (c *JiraClient) recordRateLimit(resp *http.Response) {
+	status, ok := parseRateLimit(resp.Header)
+	if !ok {
+		return
+	}
+
+	c.rateLimitMu.Lock()
+	c.rateLimit = status
+	c.rateLimitMu.Unlock()
+}
+
+// waitForRateLimitReset holds the next request until the quota resets when
+// fewer than RateLimitThreshold requests are left, so a batch slows down
+// before Jira starts answering 429.
+func (c *JiraClient) waitForRateLimitReset(ctx context.Context) error {
+	c.rateLimitMu.Lock()
+	status := c.rateLimit
+	low := c.RateLimitThreshold > 0 && status.Known && status.Remaining < c.RateLimitThreshold
+	if low {
+		// Only the first request waits on a given report; the response to
+		// it brings a fresh one.
+		c.rateLimit = RateLimitStatus{}
+	}
+	c.rateLimitMu.Unlock()
+
+	if !low {
+		return nil
+	}
+	delay := time.Until(status.Reset)
+	if delay <= 0 {
+		return nil
+	}
+
+	c.log().Warn("Jira rate limit nearly used up; waiting for reset", "remaining", status.Remaining, "reset", status.Reset)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRateLimit reads X-RateLimit-Remaining and X-RateLimit-Reset. Jira
+// Cloud gives the reset as an ISO 8601 time, sometimes without seconds; a
+// number is taken as Unix seconds.
+func parseRateLimit(header http.Header) (RateLimitStatus, bool) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return RateLimitStatus{}, false
+	}
+
+	status := RateLimitStatus{Known: true, Remaining: remaining}
+	value := header.Get("X-RateLimit-Reset")
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		status.Reset = time.Unix(seconds, 0)
+		return status, true
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00"} {
+		if reset, err := time.Parse(layout, value); err == nil {
+			status.Reset = reset
+			break
+		}
+	}
+	return status, true
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJiraWaitsForRateLimitReset(t *testing.T) {
+	var mu sync.Mutex
+	var requests []time.Time
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, time.Now())
+		first := len(requests) == 1
+		mu.Unlock()
+
+		if first {
+			w.Header().Set("X-RateLimit-Remaining", "1")
+			w.Header().Set("X-RateLimit-Reset", time.Now().Add(300*time.Millisecond).Format(time.RFC3339Nano))
+		} else {
+			w.Header().Set("X-RateLimit-Remaining", "99")
+		}
+		w.Write([]byte(`{"issues": [], "total": 0}`))
+	}))
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+	client.RateLimitThreshold = 5
+
+	if _, err := client.FetchIssues(context.Background(), "project = TU"); err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+	if status := client.RateLimit(); !status.Known || status.Remaining != 1 {
+		t.Errorf("Expected the low remaining quota to be recorded, got %+v", status)
+	}
+
+	if _, err := client.FetchIssues(context.Background(), "project = TU"); err != nil {
+		t.Fatalf("Error fetching issues: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	if gap := requests[1].Sub(requests[0]); gap < 250*time.Millisecond {
+		t.Errorf("Expected the second request to wait for the reset, but it followed after %s", gap)
+	}
+
+	if status := client.RateLimit(); status.Remaining != 99 {
+		t.Errorf("Expected the refilled quota to be recorded, got %+v", status)
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-RateLimit-Remaining", "3")
+	header.Set("X-RateLimit-Reset", "2024-05-01T12:30Z")
+
+	status, ok := parseRateLimit(header)
+	if !ok || status.Remaining != 3 || !status.Reset.Equal(time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)) {
+		t.Errorf("Expected 3 remaining until 12:30 UTC, got %+v", status)
+	}
+
+	if _, ok := parseRateLimit(http.Header{}); ok {
+		t.Errorf("Expected no status without the headers")
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Drift is an issue whose Notion page doesn't match Jira. PageID is "" when
+// the issue has no page at all, in which case Differences is empty.
+type Drift struct {
+	Key         string
+	PageID      string
+	Differences []PropertyDiff
+}
+
+// PropertyDiff is one mapped property whose Notion value differs from the
+// value a sync would write, both in the form NotionPage.Values gives.
+type PropertyDiff struct {
+	Property string
+	Jira     interface{}
+	Notion   interface{}
+}
+
+// String describes the difference on one line.
+func (d PropertyDiff) String() string {
+	return fmt.Sprintf("%s: Jira %v, Notion %v", d.Property, d.Jira, d.Notion)
+}
+
+// Reconcile compares every issue matching jql, or defaultJQL when it is "",
+// with its current page in databaseID and returns the issues that differ. It
+// only reads from Notion. Relations are not compared.
+func Reconcile(ctx context.Context, jira *JiraClient, notion *NotionClient, mapping []FieldMapping, databaseID, jql string) ([]Drift, error) {
+	result, err := jira.FetchIssues(ctx, jql, FetchOptions{Fields: mappingFields(mapping)})
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	for _, issue := range result.Issues {
+		pageID, err := notion.FindIssuePage(ctx, databaseID, issue.Key, issue.ID)
+		if err != nil {
+			return drifts, err
+		}
+		if pageID == "" {
+			drifts = append(drifts, Drift{Key: issue.Key})
+			continue
+		}
+
+		values, err := notion.GetPage(ctx, pageID)
+		if err != nil {
+			return drifts, err
+		}
+
+		var differences []PropertyDiff
+		for _, m := range mapping {
+			expected, ok := expectedValue(notion, issue, m)
+			if !ok {
+				continue
+			}
+			if actual := values[m.NotionProperty]; !sameValue(expected, actual) {
+				differences = append(differences, PropertyDiff{Property: m.NotionProperty, Jira: expected, Notion: actual})
+			}
+		}
+		if len(differences) > 0 {
+			drifts = append(drifts, Drift{Key: issue.Key, PageID: pageID, Differences: differences})
+		}
+	}
+
+	return drifts, nil
+}
+
+// expectedValue is the value buildNotionProperties would write for m, in the
+// form NotionPage.Values reads it back. It is false for mappings that aren't
+// compared, and for values StrictValues would have refused to write.
+func expectedValue(notion *NotionClient, issue Issue, m FieldMapping) (interface{}, bool) {
+	switch m.NotionType {
+	case NotionMultiSelect:
+		values, ok := jiraFieldValues(issue, m.JiraField)
+		if !ok {
+			return nil, false
+		}
+		renamed := make([]string, 0, len(values))
+		for _, value := range values {
+			value, err := m.notionValue(value)
+			if err != nil {
+				return nil, false
+			}
+			renamed = append(renamed, value)
+		}
+		options := []string{}
+		for _, option := range multiSelectProperty(renamed)["multi_select"].([]interface{}) {
+			options = append(options, option.(map[string]interface{})["name"].(string))
+		}
+		return options, true
+	case NotionNumber:
+		value, ok := jiraFieldNumber(issue, m.JiraField, notion.log())
+		if !ok || value == nil {
+			return nil, ok
+		}
+		return *value, true
+	}
+
+	value, ok := jiraFieldValue(issue, m.JiraField)
+	if !ok {
+		return nil, false
+	}
+	if m.JiraField == "key" && m.NotionProperty == notion.JiraKeyProperty {
+		value = externalID(notion.JiraInstance, value)
+	}
+
+	if m.NotionType != NotionDate {
+		var err error
+		if value, err = m.notionValue(value); err != nil {
+			return nil, false
+		}
+	}
+
+	switch m.NotionType {
+	case NotionTitle, NotionRichText, NotionURL:
+		return value, true
+	case NotionSelect:
+		if value == "" {
+			return nil, true
+		}
+		return value, true
+	case NotionDate:
+		if value == "" {
+			return nil, true
+		}
+		if t, err := parseJiraTime(value); err == nil {
+			return t, true
+		}
+		if t, err := parseNotionDate(value); err == nil {
+			return t, true
+		}
+		return value, true
+	}
+	return nil, false
+}
+
+// sameValue compares an expected and a stored value. Times match to the
+// minute, as Notion drops seconds, and text ignores surrounding whitespace.
+func sameValue(expected, actual interface{}) bool {
+	switch e := expected.(type) {
+	case time.Time:
+		a, ok := actual.(time.Time)
+		return ok && e.Truncate(time.Minute).Equal(a.Truncate(time.Minute))
+	case string:
+		a, ok := actual.(string)
+		return ok && strings.TrimSpace(e) == strings.TrimSpace(a)
+	}
+	return reflect.DeepEqual(expected, actual)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReconcileReportsStatusMismatch(t *testing.T) {
+	jiraServer := jiraSearchServer(t, []Issue{
+		{Key: "TU-1", Fields: IssueFields{Summary: "Drifted bet", Status: &Status{Name: "In Progress"}, Updated: "2024-05-01T09:30:00.000+0100"}},
+		{Key: "TU-2", Fields: IssueFields{Summary: "Missing bet"}},
+	})
+	defer jiraServer.Close()
+
+	notionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/databases/db-1/query":
+			var body struct {
+				Filter struct {
+					RichText struct {
+						Equals string `json:"equals"`
+					} `json:"rich_text"`
+				} `json:"filter"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Fatalf("Error decoding request: %v", err)
+			}
+			results := []map[string]string{}
+			if body.Filter.RichText.Equals == "TU-1" {
+				results = append(results, map[string]string{"id": "page-1"})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"results": results, "has_more": false})
+		case r.Method == "GET" && r.URL.Path == "/pages/page-1":
+			_, _ = w.Write([]byte(`{"id": "page-1", "properties": {
+				"Jira Key": {"type": "rich_text", "rich_text": [{"plain_text": "TU-1"}]},
+				"Name": {"type": "title", "title": [{"plain_text": "Drifted bet"}]},
+				"Status": {"type": "select", "select": {"name": "Done"}},
+				"Assignee": {"type": "rich_text", "rich_text": []},
+				"Priority": {"type": "select", "select": null},
+				"Updated": {"type": "date", "date": {"start": "2024-05-01T08:30:00.000+00:00"}}
+			}}`))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer notionServer.Close()
+
+	drifts, err := Reconcile(context.Background(), newTestJiraClient(t, jiraServer.URL), newTestNotionClient(notionServer.URL), defaultFieldMapping, "db-1", "")
+	if err != nil {
+		t.Fatalf("Error reconciling: %v", err)
+	}
+
+	if len(drifts) != 2 {
+		t.Fatalf("Expected 2 drifted issues, got %+v", drifts)
+	}
+
+	if drifts[0].Key != "TU-1" || len(drifts[0].Differences) != 1 {
+		t.Fatalf("Expected TU-1 to differ only in status, got %+v", drifts[0])
+	}
+	diff := drifts[0].Differences[0]
+	if diff.Property != "Status" || diff.Jira != "In Progress" || diff.Notion != "Done" {
+		t.Errorf("Expected Status: Jira In Progress, Notion Done, got %s", diff)
+	}
+
+	if drifts[1].Key != "TU-2" || drifts[1].PageID != "" {
+		t.Errorf("Expected TU-2 to be reported as having no page, got %+v", drifts[1])
+	}
+}
+
+func TestReconcileFindsMovedIssueByID(t *testing.T) {
+	// TU-1 moved to OPS and is now OPS-1; its page holds the old key.
+	jiraServer := jiraSearchServer(t, []Issue{{ID: "10001", Key: "OPS-1", Fields: IssueFields{Summary: "Moved bet"}}})
+	defer jiraServer.Close()
+
+	server := newNotionTestServer(t)
+	server.Query = &notionResponse{Body: `{"results": [{"id": "page-TU-1"}], "has_more": false}`}
+	server.Get = &notionResponse{Body: `{"id": "page-TU-1", "properties": {
+		"Name": {"type": "title", "title": [{"plain_text": "Moved bet"}]}
+	}}`}
+	notion := server.NotionClient()
+	notion.JiraIDProperty = "Jira ID"
+
+	mapping := []FieldMapping{{JiraField: "summary", NotionProperty: "Name", NotionType: NotionTitle}}
+	drifts, err := Reconcile(context.Background(), newTestJiraClient(t, jiraServer.URL), notion, mapping, "db-1", "")
+	if err != nil {
+		t.Fatalf("Error reconciling: %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("Expected the moved issue to match its page, got %+v", drifts)
+	}
+
+	query, _ := server.LastRequest("POST", "/databases/db-1/query")
+	assertJSON(t, query.Body["filter"], `{"property": "Jira ID", "rich_text": {"equals": "10001"}}`)
+}
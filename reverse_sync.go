@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ReverseSyncConfig describes where status lives on each side of a reverse
+// sync.
+type ReverseSyncConfig struct {
+	// JQL scopes the Jira issues considered, as for a forward sync.
+	JQL        string
+	DatabaseID string
+	// StatusProperty is the Notion select or status property PMs edit.
+	StatusProperty string
+	// StatusFieldID is the Jira option field the Notion status is written to.
+	StatusFieldID string
+	// LastSynced is when the previous sync finished. Changes on either side
+	// are detected by comparing against it.
+	LastSynced time.Time
+}
+
+// ReverseSyncResult lists the Jira keys acted on by ReverseSync.
+type ReverseSyncResult struct {
+	Pushed    []string
+	Conflicts []string
+	Failed    []string
+}
+
+// ReverseSync pushes status changes made in Notion back to Jira. A page is
+// pushed when its status differs from Jira's and only the Notion side has
+// changed since cfg.LastSynced. When both sides have changed the issue is
+// logged as a conflict and left alone. Per-issue failures don't stop the run;
+// they are joined into the returned error.
+func ReverseSync(ctx context.Context, jira *JiraClient, notion *NotionClient, cfg ReverseSyncConfig) (ReverseSyncResult, error) {
+	var result ReverseSyncResult
+
+	fetched, err := jira.FetchIssues(ctx, cfg.JQL, FetchOptions{Fields: []string{"key", "updated", cfg.StatusFieldID}})
+	if err != nil {
+		return result, err
+	}
+	byKey := make(map[string]Issue, len(fetched.Issues))
+	for _, issue := range fetched.Issues {
+		byKey[issue.Key] = issue
+	}
+
+	pages, err := notion.QueryDatabase(ctx, cfg.DatabaseID, nil)
+	if err != nil {
+		return result, err
+	}
+
+	var errs []error
+	for _, page := range pages {
+		key, ok := issueKeyOf(notion.JiraInstance, page.PlainText(notion.JiraKeyProperty))
+		if !ok {
+			continue
+		}
+		issue, ok := byKey[key]
+		if !ok {
+			continue
+		}
+
+		notionStatus := page.SelectName(cfg.StatusProperty)
+		if notionStatus == "" || notionStatus == issue.CustomFieldString(cfg.StatusFieldID) {
+			continue
+		}
+
+		notionChanged := page.LastEditedTime.After(cfg.LastSynced)
+		jiraChanged := true
+		if updated, err := parseJiraTime(issue.Fields.Updated); err == nil {
+			jiraChanged = updated.After(cfg.LastSynced)
+		}
+
+		switch {
+		case notionChanged && jiraChanged:
+			jira.log().Warn("status conflict, skipping", "issueKey", key, "notionStatus", notionStatus, "jiraStatus", issue.CustomFieldString(cfg.StatusFieldID))
+			result.Conflicts = append(result.Conflicts, key)
+		case notionChanged:
+			_, _, err := jira.UpdateCustomField(ctx, key, cfg.StatusFieldID, map[string]interface{}{"value": notionStatus})
+			if err != nil {
+				result.Failed = append(result.Failed, key)
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				continue
+			}
+			result.Pushed = append(result.Pushed, key)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type recordedWrite struct {
+	path string
+	body []byte
+}
+
+func reverseSyncServers(t *testing.T, jiraUpdated, notionEdited string, writes *[]recordedWrite) (*JiraClient, *NotionClient, func()) {
+	t.Helper()
+
+	jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			body, _ := io.ReadAll(r.Body)
+			*writes = append(*writes, recordedWrite{path: r.URL.Path, body: body})
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		_, err := w.Write([]byte(`{"total": 1, "issues": [{"key": "TU-1", "fields": {
+			"updated": "` + jiraUpdated + `",
+			"customfield_10600": {"value": "In Progress"}
+		}}]}`))
+		if err != nil {
+			t.Fatalf("Error writing response: %v", err)
+		}
+	}))
+
+	notionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"has_more": false, "results": [{
+			"id": "page-1",
+			"last_edited_time": "` + notionEdited + `",
+			"properties": {
+				"Jira Key": {"type": "rich_text", "rich_text": [{"plain_text": "TU-1"}]},
+				"Status": {"type": "select", "select": {"name": "Done"}}
+			}
+		}]}`))
+		if err != nil {
+			t.Fatalf("Error writing response: %v", err)
+		}
+	}))
+
+	jira := newTestJiraClient(t, jiraServer.URL)
+	notion := newTestNotionClient(notionServer.URL)
+
+	return jira, notion, func() {
+		jiraServer.Close()
+		notionServer.Close()
+	}
+}
+
+func reverseSyncConfig() ReverseSyncConfig {
+	return ReverseSyncConfig{
+		DatabaseID:     "db-1",
+		StatusProperty: "Status",
+		StatusFieldID:  "customfield_10600",
+		LastSynced:     time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestReverseSyncPushesNotionChange(t *testing.T) {
+	var writes []recordedWrite
+	jira, notion, cleanup := reverseSyncServers(t, "2024-02-28T10:00:00.000+0000", "2024-03-02T09:00:00.000Z", &writes)
+	defer cleanup()
+
+	result, err := ReverseSync(context.Background(), jira, notion, reverseSyncConfig())
+	if err != nil {
+		t.Fatalf("Error running reverse sync: %v", err)
+	}
+
+	if len(result.Pushed) != 1 || result.Pushed[0] != "TU-1" {
+		t.Errorf("Expected TU-1 to be pushed, got %+v", result)
+	}
+
+	if len(writes) != 1 {
+		t.Fatalf("Expected %d write to Jira, got %d", 1, len(writes))
+	}
+
+	if writes[0].path != "/rest/api/2/issue/TU-1" {
+		t.Errorf("Expected write to %s, got %s", "/rest/api/2/issue/TU-1", writes[0].path)
+	}
+
+	var body map[string]map[string]map[string]string
+	if err := json.Unmarshal(writes[0].body, &body); err != nil {
+		t.Fatalf("Error decoding write: %v", err)
+	}
+	if got := body["fields"]["customfield_10600"]["value"]; got != "Done" {
+		t.Errorf("Expected status %s to be written, got %s", "Done", got)
+	}
+}
+
+func TestReverseSyncSkipsConflicts(t *testing.T) {
+	var writes []recordedWrite
+	jira, notion, cleanup := reverseSyncServers(t, "2024-03-02T10:00:00.000+0000", "2024-03-02T09:00:00.000Z", &writes)
+	defer cleanup()
+
+	result, err := ReverseSync(context.Background(), jira, notion, reverseSyncConfig())
+	if err != nil {
+		t.Fatalf("Error running reverse sync: %v", err)
+	}
+
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != "TU-1" {
+		t.Errorf("Expected TU-1 to be a conflict, got %+v", result)
+	}
+
+	if len(writes) != 0 {
+		t.Errorf("Expected no writes to Jira, got %v", writes)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SprintFieldID is the custom field that holds an issue's sprints, used by the
+// "sprint" mapping field. customfield_10020 is Jira Cloud's usual id; look up
+// others with GetCustomFieldID(ctx, "Sprint").
+var SprintFieldID = "customfield_10020"
+
+// Sprint is one entry of a sprint custom field, or a sprint of a board as
+// listed by FetchBoardSprints. The dates are zero for sprints that haven't
+// been planned or started, and legacy sprint fields don't carry them.
+type Sprint struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+}
+
+// legacySprintAttr matches the start of each attribute in Jira Server's
+// stringified sprints, e.g.
+// "com.atlassian.greenhopper.service.sprint.Sprint@1f[id=3,state=ACTIVE,name=Sprint 3,...]".
+var legacySprintAttr = regexp.MustCompile(`[\[,](\w+)=`)
+
+// SprintName returns the name of the issue's active sprint in the custom
+// field fieldID, or of its latest sprint when none is active. It yields ""
+// when the issue has no sprint or the field can't be parsed.
+func (i Issue) SprintName(fieldID string) string {
+	raw, ok := i.Fields.Custom[fieldID]
+	if !ok {
+		return ""
+	}
+
+	sprints := parseSprints(raw)
+	if len(sprints) == 0 {
+		return ""
+	}
+	for _, sprint := range sprints {
+		if strings.EqualFold(sprint.State, "active") {
+			return sprint.Name
+		}
+	}
+	return sprints[len(sprints)-1].Name
+}
+
+// parseSprints decodes a sprint field in either the object array format of
+// current Jira or the legacy array of strings.
+func parseSprints(raw json.RawMessage) []Sprint {
+	var sprints []Sprint
+	if err := json.Unmarshal(raw, &sprints); err == nil {
+		return sprints
+	}
+
+	var legacy []string
+	if err := json.Unmarshal(raw, &legacy); err != nil {
+		return nil
+	}
+	for _, value := range legacy {
+		if sprint, ok := parseLegacySprint(value); ok {
+			sprints = append(sprints, sprint)
+		}
+	}
+	return sprints
+}
+
+func parseLegacySprint(value string) (Sprint, bool) {
+	value = strings.TrimSuffix(value, "]")
+	matches := legacySprintAttr.FindAllStringSubmatchIndex(value, -1)
+	if len(matches) == 0 {
+		return Sprint{}, false
+	}
+
+	var sprint Sprint
+	for n, match := range matches {
+		end := len(value)
+		if n+1 < len(matches) {
+			end = matches[n+1][0]
+		}
+		// Values are unquoted, so a name containing ",x=" is cut short; Jira
+		// has the same ambiguity.
+		attr, attrValue := value[match[2]:match[3]], value[match[1]:end]
+		switch attr {
+		case "name":
+			sprint.Name = attrValue
+		case "state":
+			sprint.State = attrValue
+		}
+	}
+	return sprint, sprint.Name != ""
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSprintName(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    string
+		expected string
+	}{
+		{"objects", `[{"id":1,"name":"Sprint 1","state":"closed"},{"id":2,"name":"Sprint 2","state":"active"},{"id":3,"name":"Sprint 3","state":"future"}]`, "Sprint 2"},
+		{"legacy", `["com.atlassian.greenhopper.service.sprint.Sprint@1a2b[id=1,rapidViewId=4,state=CLOSED,name=Sprint 1,startDate=2024-01-01T00:00:00.000Z,endDate=2024-01-14T00:00:00.000Z,completeDate=<null>,sequence=1,goal=]","com.atlassian.greenhopper.service.sprint.Sprint@3c4d[id=2,rapidViewId=4,state=ACTIVE,name=Sprint 2, Growth,startDate=2024-01-15T00:00:00.000Z,endDate=<null>,completeDate=<null>,sequence=2,goal=Ship onboarding]"]`, "Sprint 2, Growth"},
+		{"none active", `[{"id":1,"name":"Sprint 1","state":"closed"},{"id":2,"name":"Sprint 2","state":"closed"}]`, "Sprint 2"},
+		{"null", `null`, ""},
+		{"empty", `[]`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issue := Issue{Fields: IssueFields{Custom: map[string]json.RawMessage{"customfield_10020": json.RawMessage(tt.field)}}}
+			if got := issue.SprintName("customfield_10020"); got != tt.expected {
+				t.Errorf("Expected sprint %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	if got := (Issue{}).SprintName("customfield_10020"); got != "" {
+		t.Errorf("Expected no sprint, got %q", got)
+	}
+}
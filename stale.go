@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// StaleAction is what SyncJiraToNotion does with a page whose Jira issue no
+// longer matches the sync JQL, for example because it was closed or moved to
+// another project.
+type StaleAction int
+
+const (
+	// StaleIgnore leaves stale pages untouched.
+	StaleIgnore StaleAction = iota
+	// StaleArchive moves stale pages to Notion's trash.
+	StaleArchive
+	// StaleMark ticks the page's Stale checkbox, which must exist in the
+	// database.
+	StaleMark
+)
+
+// staleProperty is the checkbox property set by StaleMark.
+const staleProperty = "Stale"
+
+// ParseStaleAction parses "ignore", "archive" or "mark", case-insensitively.
+// An empty string is StaleIgnore.
+func ParseStaleAction(value string) (StaleAction, error) {
+	switch strings.ToLower(value) {
+	case "", "ignore":
+		return StaleIgnore, nil
+	case "archive":
+		return StaleArchive, nil
+	case "mark":
+		return StaleMark, nil
+	}
+	return StaleIgnore, fmt.Errorf("unknown stale action %q (expected ignore, archive or mark)", value)
+}
+
+// handleStalePages applies options.Stale to every page in the database whose
+// Jira key is not in scope, and returns how many pages it changed. Pages
+// without a Jira key weren't created by the sync and are left alone. Each
+// handled page is forgotten by options.State, so an issue that comes back into
+// scope unchanged still gets its page restored.
+//
+// An incremental run only fetches recently updated issues, and a resumed one
+// passes nil fetched issues, so then the full JQL is re-run to learn which
+// keys are still in scope.
+func handleStalePages(ctx context.Context, jira *JiraClient, notion *NotionClient, databaseID string, options SyncOptions, fetched []Issue) (int, error) {
+	if !options.LastSynced.IsZero() || fetched == nil {
+		result, err := jira.FetchIssues(ctx, options.JQL)
+		if err != nil {
+			return 0, err
+		}
+		fetched = result.Issues
+	}
+
+	inScope := map[string]bool{}
+	for _, issue := range fetched {
+		inScope[issue.Key] = true
+	}
+
+	pages, err := notion.QueryDatabase(ctx, databaseID, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var stalePages []NotionPage
+	var staleKeys []string
+	synced := 0
+	for _, page := range pages {
+		key, ok := issueKeyOf(notion.JiraInstance, page.PlainText(notion.JiraKeyProperty))
+		if !ok || key == "" {
+			continue
+		}
+		synced++
+		if !inScope[key] {
+			stalePages = append(stalePages, page)
+			staleKeys = append(staleKeys, key)
+		}
+	}
+
+	if options.Stale == StaleArchive {
+		if err := checkArchiveLimits(len(stalePages), synced, options); err != nil {
+			return 0, err
+		}
+	}
+
+	stale := 0
+	for i, page := range stalePages {
+		key := staleKeys[i]
+
+		if options.Stale == StaleArchive {
+			err = notion.ArchivePage(ctx, page.ID)
+		} else {
+			err = notion.UpdatePage(ctx, page.ID, map[string]interface{}{
+				staleProperty: map[string]interface{}{"checkbox": true},
+			})
+		}
+		if err != nil {
+			notion.log().Error("handling stale page failed", "issueKey", key, "pageID", page.ID, "error", err)
+			return stale, err
+		}
+
+		notion.log().Info("handled stale page", "issueKey", key, "pageID", page.ID)
+		stale++
+
+		if options.State != nil {
+			if err := options.State.Forget(key); err != nil {
+				return stale, err
+			}
+		}
+	}
+
+	return stale, nil
+}
+
+// checkArchiveLimits fails when archiving n of the synced pages would exceed
+// options.MaxArchive or options.MaxArchivePercent.
+func checkArchiveLimits(n, synced int, options SyncOptions) error {
+	limit := options.MaxArchive
+	if limit == 0 {
+		limit = defaultMaxArchive
+	}
+	if limit > 0 && n > limit {
+		return fmt.Errorf("refusing to archive %d stale pages, more than the limit of %d per run; check the JQL or raise the limit", n, limit)
+	}
+
+	percent := options.MaxArchivePercent
+	if percent == 0 {
+		percent = defaultMaxArchivePercent
+	}
+	if percent > 0 && synced > 0 && float64(n)*100 > percent*float64(synced) {
+		return fmt.Errorf("refusing to archive %d of %d synced pages, more than the limit of %g%% per run; check the JQL or raise the limit", n, synced, percent)
+	}
+	return nil
+}
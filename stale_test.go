@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSyncArchivesStalePages(t *testing.T) {
+	jiraServer := jiraSearchServer(t, []Issue{{Key: "TU-1", Fields: IssueFields{Summary: "Still in scope"}}})
+	defer jiraServer.Close()
+
+	patches := map[string]string{}
+	notionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		switch r.Method {
+		case "POST":
+			var query struct {
+				Filter json.RawMessage `json:"filter"`
+			}
+			if err := json.Unmarshal(body, &query); err != nil {
+				t.Fatalf("Error decoding request: %v", err)
+			}
+
+			if query.Filter != nil {
+				w.Write([]byte(`{"results": [{"id": "page-1"}]}`))
+				return
+			}
+			w.Write([]byte(`{"results": [
+				{"id": "page-1", "properties": {"Jira Key": {"rich_text": [{"plain_text": "TU-1"}]}}},
+				{"id": "page-2", "properties": {"Jira Key": {"rich_text": [{"plain_text": "TU-2"}]}}},
+				{"id": "page-3", "properties": {"Jira Key": {"rich_text": []}}}
+			]}`))
+		case "PATCH":
+			patches[r.URL.Path] = string(body)
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer notionServer.Close()
+
+	jira := newTestJiraClient(t, jiraServer.URL)
+	report, err := SyncJiraToNotion(context.Background(), jira, newTestNotionClient(notionServer.URL), defaultFieldMapping, "db-1", SyncOptions{Stale: StaleArchive})
+	if err != nil {
+		t.Fatalf("Error syncing: %v", err)
+	}
+
+	if report.Stale != 1 {
+		t.Errorf("Expected 1 stale page, got %d", report.Stale)
+	}
+
+	if got := patches["/pages/page-2"]; got != `{"archived":true}` {
+		t.Errorf("Expected page-2 to be archived, got %q", got)
+	}
+
+	if _, ok := patches["/pages/page-3"]; ok {
+		t.Error("Expected the page without a Jira key to be left alone")
+	}
+}
+
+func TestSyncArchiveLimit(t *testing.T) {
+	jiraServer := jiraSearchServer(t, []Issue{{Key: "TU-1"}})
+	defer jiraServer.Close()
+
+	archived := 0
+	notionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		switch r.Method {
+		case "POST":
+			var query struct {
+				Filter json.RawMessage `json:"filter"`
+			}
+			if err := json.Unmarshal(body, &query); err != nil {
+				t.Fatalf("Error decoding request: %v", err)
+			}
+
+			if query.Filter != nil {
+				w.Write([]byte(`{"results": [{"id": "page-1"}]}`))
+				return
+			}
+			w.Write([]byte(`{"results": [
+				{"id": "page-1", "properties": {"Jira Key": {"rich_text": [{"plain_text": "TU-1"}]}}},
+				{"id": "page-2", "properties": {"Jira Key": {"rich_text": [{"plain_text": "TU-2"}]}}},
+				{"id": "page-3", "properties": {"Jira Key": {"rich_text": [{"plain_text": "TU-3"}]}}},
+				{"id": "page-4", "properties": {"Jira Key": {"rich_text": [{"plain_text": "TU-4"}]}}}
+			]}`))
+		case "PATCH":
+			if string(body) == `{"archived":true}` {
+				archived++
+			}
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer notionServer.Close()
+
+	jira := newTestJiraClient(t, jiraServer.URL)
+	notion := newTestNotionClient(notionServer.URL)
+
+	_, err := SyncJiraToNotion(context.Background(), jira, notion, defaultFieldMapping, "db-1", SyncOptions{Stale: StaleArchive, MaxArchive: 2, MaxArchivePercent: -1})
+	if err == nil || !strings.Contains(err.Error(), "refusing to archive 3 stale pages") {
+		t.Errorf("Expected the run to abort over the archive limit, got %v", err)
+	}
+
+	_, err = SyncJiraToNotion(context.Background(), jira, notion, defaultFieldMapping, "db-1", SyncOptions{Stale: StaleArchive, MaxArchive: -1})
+	if err == nil || !strings.Contains(err.Error(), "refusing to archive 3 of 4 synced pages") {
+		t.Errorf("Expected the run to abort over the default percentage limit, got %v", err)
+	}
+
+	if archived != 0 {
+		t.Errorf("Expected no pages to be archived, got %d", archived)
+	}
+}
+
+func TestParseStaleAction(t *testing.T) {
+	tests := map[string]StaleAction{"": StaleIgnore, "ignore": StaleIgnore, "Archive": StaleArchive, "mark": StaleMark}
+	for value, expected := range tests {
+		got, err := ParseStaleAction(value)
+		if err != nil || got != expected {
+			t.Errorf("Expected ParseStaleAction(%q) to be %v, got %v, %v", value, expected, got, err)
+		}
+	}
+
+	if _, err := ParseStaleAction("delete"); err == nil {
+		t.Error("Expected an error for an unknown action")
+	}
+}
+
+func TestSyncRestoresStalePagesBackInScope(t *testing.T) {
+	for _, action := range []StaleAction{StaleArchive, StaleMark} {
+		var issues []Issue
+		jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewEncoder(w).Encode(IssueResponse{Total: len(issues), Issues: issues}); err != nil {
+				t.Fatalf("Error encoding response: %v", err)
+			}
+		}))
+		defer jiraServer.Close()
+
+		notion := &fakeNotion{pages: map[string]string{}}
+		notionServer := httptest.NewServer(notion.handler(t))
+		defer notionServer.Close()
+
+		path := filepath.Join(t.TempDir(), "state.json")
+		runSync := func(keys ...string) SyncReport {
+			t.Helper()
+
+			issues = nil
+			for _, key := range keys {
+				issues = append(issues, Issue{Key: key, Fields: IssueFields{Summary: "Bet " + key}})
+			}
+			state, err := LoadSyncState(path, nil)
+			if err != nil {
+				t.Fatalf("Error loading state: %v", err)
+			}
+			report, err := SyncJiraToNotion(context.Background(), newTestJiraClient(t, jiraServer.URL), newTestNotionClient(notionServer.URL), defaultFieldMapping, "db-1", SyncOptions{State: state, Stale: action, MaxArchive: -1, MaxArchivePercent: -1})
+			if err != nil {
+				t.Fatalf("Error syncing: %v", err)
+			}
+			return report
+		}
+
+		runSync("TU-1", "TU-2")
+		if report := runSync("TU-1"); report.Stale != 1 {
+			t.Fatalf("Expected TU-2 to be stale with action %d, got %+v", action, report)
+		}
+		report := runSync("TU-1", "TU-2")
+
+		switch action {
+		case StaleArchive:
+			if report.Created != 1 || notion.pages["TU-2"] == "" {
+				t.Errorf("Expected the archived TU-2 to be created again, got %+v and pages %v", report, notion.pages)
+			}
+		case StaleMark:
+			if report.Updated != 1 || notion.stale["page-TU-2"] {
+				t.Errorf("Expected TU-2 to be updated with its Stale flag cleared, got %+v and flags %v", report, notion.stale)
+			}
+		}
+	}
+}
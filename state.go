@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SyncState records which issue keys the current run has finished, so an
+// interrupted run can resume without redoing them, and a hash of what was
+// last written for each key, so unchanged issues can be skipped on later
+// runs. It also remembers the Notion comments PushNotionComments has posted
+// to Jira, and, for batched runs, the search cursor to resume from. It is safe
+// for concurrent use.
+type SyncState struct {
+	path string
+
+	mu        sync.Mutex
+	completed map[string]bool
+	hashes    map[string]string
+	comments  map[string]string
+	cursors   map[string]string
+}
+
+type syncStateFile struct {
+	Completed []string          `json:"completed"`
+	Hashes    map[string]string `json:"hashes,omitempty"`
+	// Comments maps posted Notion comment ids to their Jira comment ids.
+	Comments map[string]string `json:"comments,omitempty"`
+	// Cursors maps each search's JQL to the FetchResult.Cursor to resume it
+	// from.
+	Cursors map[string]string `json:"cursors,omitempty"`
+}
+
+// LoadSyncState reads the state left at path by an interrupted run. A missing
+// file starts a fresh run; so does a corrupt one, after logging a warning.
+func LoadSyncState(path string, logger *slog.Logger) (*SyncState, error) {
+	if logger == nil {
+		logger = discardLogger
+	}
+
+	state := &SyncState{path: path, completed: map[string]bool{}, hashes: map[string]string{}, comments: map[string]string{}, cursors: map[string]string{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file syncStateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		logger.Warn("ignoring corrupt sync state, starting fresh", "path", path, "error", err)
+		return state, nil
+	}
+
+	for _, key := range file.Completed {
+		state.completed[key] = true
+	}
+	for key, hash := range file.Hashes {
+		state.hashes[key] = hash
+	}
+	for notionID, jiraID := range file.Comments {
+		state.comments[notionID] = jiraID
+	}
+	for jql, cursor := range file.Cursors {
+		state.cursors[jql] = cursor
+	}
+	if len(state.completed) > 0 {
+		logger.Info("resuming interrupted sync", "path", path, "completed", len(state.completed))
+	}
+
+	return state, nil
+}
+
+// IsDone reports whether key was already completed by this run.
+func (s *SyncState) IsDone(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.completed[key]
+}
+
+// MarkDone records key as completed and persists the state.
+func (s *SyncState) MarkDone(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.completed[key] = true
+	return s.saveLocked()
+}
+
+// MarkSynced records key as completed with the hash of what was written for
+// it, and persists the state.
+func (s *SyncState) MarkSynced(key, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.completed[key] = true
+	s.hashes[key] = hash
+	return s.saveLocked()
+}
+
+// Hash returns the hash recorded for key by MarkSynced, or "" if none was.
+func (s *SyncState) Hash(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hashes[key]
+}
+
+// Forget drops key's completion and hash, so the next run writes its page
+// again, and persists the state.
+func (s *SyncState) Forget(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.completed, key)
+	delete(s.hashes, key)
+	return s.saveLocked()
+}
+
+// Cursor returns the search cursor recorded for jql by SetCursor, or "".
+func (s *SyncState) Cursor(jql string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cursors[jql]
+}
+
+// SetCursor records where the search for jql should resume, and persists the
+// state.
+func (s *SyncState) SetCursor(jql, cursor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cursors[jql] = cursor
+	return s.saveLocked()
+}
+
+// CommentPosted reports whether the Notion comment notionID was posted to
+// Jira.
+func (s *SyncState) CommentPosted(notionID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.comments[notionID]
+	return ok
+}
+
+// MarkCommentPosted records that the Notion comment notionID was posted as
+// the Jira comment jiraID, and persists the state.
+func (s *SyncState) MarkCommentPosted(notionID, jiraID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.comments[notionID] = jiraID
+	return s.saveLocked()
+}
+
+// Clear forgets the completed keys and cursors once a run has finished
+// cleanly. Hashes and posted comments are kept for the next run; the state
+// file is removed if there are none.
+func (s *SyncState) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.completed = map[string]bool{}
+	s.cursors = map[string]string{}
+	if len(s.hashes) > 0 || len(s.comments) > 0 {
+		return s.saveLocked()
+	}
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// saveLocked writes the state to a temporary file and renames it into place,
+// so a crash mid-write leaves the previous state intact.
+func (s *SyncState) saveLocked() error {
+	file := syncStateFile{Completed: make([]string, 0, len(s.completed)), Hashes: s.hashes, Comments: s.comments, Cursors: s.cursors}
+	for key := range s.completed {
+		file.Completed = append(file.Completed, key)
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
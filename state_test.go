@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSyncStateResumesAfterPartialRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	keys := []string{"TU-1", "TU-2", "TU-3", "TU-4"}
+
+	state, err := LoadSyncState(path, nil)
+	if err != nil {
+		t.Fatalf("Error loading state: %v", err)
+	}
+
+	// The first run dies after pushing two issues.
+	for _, key := range keys[:2] {
+		if err := state.MarkDone(key); err != nil {
+			t.Fatalf("Error saving state: %v", err)
+		}
+	}
+
+	resumed, err := LoadSyncState(path, nil)
+	if err != nil {
+		t.Fatalf("Error loading state: %v", err)
+	}
+
+	var processed []string
+	for _, key := range keys {
+		if resumed.IsDone(key) {
+			continue
+		}
+		processed = append(processed, key)
+		if err := resumed.MarkDone(key); err != nil {
+			t.Fatalf("Error saving state: %v", err)
+		}
+	}
+
+	if strings.Join(processed, ",") != "TU-3,TU-4" {
+		t.Errorf("Expected only TU-3 and TU-4 to be processed, got %v", processed)
+	}
+
+	if err := resumed.Clear(); err != nil {
+		t.Fatalf("Error clearing state: %v", err)
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected state file to be removed, got %v", err)
+	}
+}
+
+func TestSyncStateCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte(`{"completed": ["TU-1"`), 0o644); err != nil {
+		t.Fatalf("Error writing state: %v", err)
+	}
+
+	var buf bytes.Buffer
+	state, err := LoadSyncState(path, slog.New(slog.NewTextHandler(&buf, nil)))
+	if err != nil {
+		t.Fatalf("Error loading state: %v", err)
+	}
+
+	if state.IsDone("TU-1") {
+		t.Error("Expected a corrupt state file to start fresh")
+	}
+
+	if !strings.Contains(buf.String(), "level=WARN") {
+		t.Errorf("Expected a warning to be logged, got %s", buf.String())
+	}
+}
+
+func TestSyncStateCursor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := LoadSyncState(path, nil)
+	if err != nil {
+		t.Fatalf("Error loading state: %v", err)
+	}
+	if err := state.SetCursor("project = TU", "50"); err != nil {
+		t.Fatalf("Error saving cursor: %v", err)
+	}
+
+	resumed, err := LoadSyncState(path, nil)
+	if err != nil {
+		t.Fatalf("Error loading state: %v", err)
+	}
+	if got := resumed.Cursor("project = TU"); got != "50" {
+		t.Errorf("Expected the cursor to survive a restart, got %q", got)
+	}
+
+	if err := resumed.Clear(); err != nil {
+		t.Fatalf("Error clearing state: %v", err)
+	}
+	if got := resumed.Cursor("project = TU"); got != "" {
+		t.Errorf("Expected a clean run to forget the cursor, got %q", got)
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SyncOptions tunes a SyncJiraToNotion run. The zero value syncs every issue
+// matching defaultJQL.
+type SyncOptions struct {
+	JQL string
+	// LastSynced, when set, limits the run to issues updated since then; see
+	// incrementalJQL.
+	LastSynced time.Time
+	// State, when set, lets an interrupted run skip issues it already
+	// pushed, and any run skip issues whose properties are unchanged since
+	// the last write. Completed keys are cleared once a run finishes without
+	// failures.
+	State *SyncState
+	// Stale selects what happens to pages whose issue no longer matches the
+	// JQL. The default, StaleIgnore, leaves them alone.
+	Stale StaleAction
+	// MaxArchive and MaxArchivePercent guard StaleArchive against a
+	// mistyped JQL: a run that would archive more pages than MaxArchive, or
+	// more than MaxArchivePercent of the synced pages, archives none and
+	// fails. Zero means defaultMaxArchive and defaultMaxArchivePercent;
+	// negative means no limit.
+	MaxArchive        int
+	MaxArchivePercent float64
+	// ShutdownTimeout is how long the issue being written when ctx is
+	// cancelled may take to finish, so its page and state entry stay in
+	// step. Zero means defaultShutdownTimeout.
+	ShutdownTimeout time.Duration
+	// IssueTimeout bounds the writes for one issue, retries included, so a
+	// hung request fails that issue rather than using up ctx's deadline.
+	// Zero means defaultIssueTimeout; negative means no limit.
+	IssueTimeout time.Duration
+	// BatchSize, when set, fetches and writes issues this many at a time. With
+	// State, the search cursor is saved after each batch without failures,
+	// so a large import that crashes resumes near where it stopped instead
+	// of fetching every page again.
+	BatchSize int
+	// Progress, when set, is called after every ProgressEvery issues
+	// processed, for a progress bar or a heartbeat in the logs, and once
+	// more with the final count when the run ends, even if it fails or is
+	// interrupted. Zero ProgressEvery means defaultProgressEvery. Progress
+	// runs on the sync's goroutine, so it should return quickly.
+	Progress      func(Progress)
+	ProgressEvery int
+	// Metadata is written to each page alongside its mapped fields; see
+	// NotionSyncer.Metadata. SyncTo ignores it.
+	Metadata PageMetadata
+	// Fields are the Jira fields SyncTo fetches, defaulting to defaultFields.
+	// SyncJiraToNotion and SyncRoutes fetch what their mapping needs instead.
+	Fields []string
+}
+
+const (
+	// defaultShutdownTimeout bounds the wait for an in-flight write on
+	// shutdown.
+	defaultShutdownTimeout = 30 * time.Second
+	// defaultIssueTimeout is the default SyncOptions.IssueTimeout.
+	defaultIssueTimeout = 2 * time.Minute
+	// defaultProgressEvery is the default SyncOptions.ProgressEvery.
+	defaultProgressEvery = 100
+)
+
+// Progress is how far a sync has got, as passed to SyncOptions.Progress.
+// Processed counts the issues written, skipped or failed so far, and Failed
+// those that failed. Total is the number of issues found so far; it grows as
+// batches or routes are fetched, and Jira's v3 search doesn't report totals
+// in advance. Issues an earlier route already synced aren't counted.
+type Progress struct {
+	Processed int
+	Total     int
+	Failed    int
+}
+
+// Defaults for SyncOptions.MaxArchive and MaxArchivePercent.
+const (
+	defaultMaxArchive        = 50
+	defaultMaxArchivePercent = 50
+)
+
+// SyncReport is a machine-readable account of what a sync run did, so
+// callers can exit non-zero or alert when Failed is non-empty.
+type SyncReport struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	// Skipped counts issues SyncState recorded as done by an earlier,
+	// interrupted run, or whose properties haven't changed since they were
+	// last written.
+	Skipped int          `json:"skipped"`
+	Failed  []FailedItem `json:"failed"`
+	// Stale is the number of pages archived or marked by the Stale option.
+	Stale    int           `json:"stale"`
+	Duration time.Duration `json:"duration"`
+	// Watermark is the latest updated time among the fetched issues, or
+	// LastSynced if none is newer. It is only safe to persist when Failed is
+	// empty.
+	Watermark time.Time `json:"watermark"`
+
+	// total is Progress.Total.
+	total int
+}
+
+// processed is the number of issues the run has written, skipped or failed.
+func (r SyncReport) processed() int {
+	return r.Created + r.Updated + r.Skipped + len(r.Failed)
+}
+
+// reportProgress calls options.Progress if report has reached another
+// multiple of options.ProgressEvery or, when final, with the run's last
+// count, unless that multiple was just reported.
+func reportProgress(options SyncOptions, report *SyncReport, final bool) {
+	if options.Progress == nil {
+		return
+	}
+	every := options.ProgressEvery
+	if every <= 0 {
+		every = defaultProgressEvery
+	}
+	processed := report.processed()
+	due := processed > 0 && processed%every == 0
+	if final {
+		due = !due
+	}
+	if due {
+		options.Progress(Progress{Processed: processed, Total: report.total, Failed: len(report.Failed)})
+	}
+}
+
+// FailedItem is an issue that couldn't be written to Notion.
+type FailedItem struct {
+	Key   string `json:"key"`
+	Error string `json:"error"`
+}
+
+// String summarises the report on one line.
+func (r SyncReport) String() string {
+	return fmt.Sprintf("created %d, updated %d, skipped %d, failed %d, stale %d in %s",
+		r.Created, r.Updated, r.Skipped, len(r.Failed), r.Stale, r.Duration.Round(time.Millisecond))
+}
+
+// SyncJiraToNotion fetches the issues matching the configured JQL and creates
+// or updates the Notion page for each, finding existing pages by notion's
+// JiraKeyProperty. A failure on one issue is logged and recorded in the
+// report without stopping the run; only a failed fetch returns an error.
+func SyncJiraToNotion(ctx context.Context, jira *JiraClient, notion *NotionClient, mapping []FieldMapping, databaseID string, opts ...SyncOptions) (SyncReport, error) {
+	var options SyncOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	return SyncRoutes(ctx, jira, notion, mapping, []Route{{JQL: options.JQL, DatabaseID: databaseID}}, options)
+}
+
+// Route sends the issues matching JQL to the Notion database DatabaseID.
+type Route struct {
+	JQL        string
+	DatabaseID string
+}
+
+// ProjectRoute routes every issue in a Jira project to databaseID.
+func ProjectRoute(projectKey, databaseID string) Route {
+	return Route{JQL: "project = " + projectKey, DatabaseID: databaseID}
+}
+
+// projectKeyPattern matches a whole Jira project key; see projectKeyExpr.
+var projectKeyPattern = regexp.MustCompile(`^` + projectKeyExpr + `$`)
+
+// projectsJQL builds a JQL query for the issues in the comma-separated
+// project keys, as given to --projects. Keys are trimmed and uppercased.
+func projectsJQL(keys string) (string, error) {
+	var projects []string
+	for _, key := range strings.Split(keys, ",") {
+		key = strings.ToUpper(strings.TrimSpace(key))
+		if key == "" {
+			return "", fmt.Errorf("empty project key in %q", keys)
+		}
+		if !projectKeyPattern.MatchString(key) {
+			return "", fmt.Errorf("invalid project key %q", key)
+		}
+		projects = append(projects, key)
+	}
+	return "project IN (" + strings.Join(projects, ", ") + ")", nil
+}
+
+// SyncRoutes is SyncJiraToNotion for several databases. Each route's issues
+// are written to its database, in order; an issue matched by more than one
+// route only goes to the first. options.JQL is ignored in favour of each
+// route's JQL.
+//
+// Cancelling ctx stops the run before the next issue. The issue being
+// written is given options.ShutdownTimeout to finish and be recorded in
+// options.State, which is then left for the next run to resume from, and
+// the cancellation is returned.
+func SyncRoutes(ctx context.Context, jira *JiraClient, notion *NotionClient, mapping []FieldMapping, routes []Route, opts ...SyncOptions) (report SyncReport, err error) {
+	var options SyncOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	start := time.Now()
+	report = SyncReport{Watermark: options.LastSynced}
+	defer func() {
+		report.Duration = time.Since(start)
+		reportProgress(options, &report, true)
+	}()
+	routed := map[string]bool{}
+
+	for _, route := range routes {
+		dest := &NotionSyncer{Client: notion, Mapping: mapping, DatabaseID: route.DatabaseID, Jira: jira, Stale: options.Stale, State: options.State, Metadata: options.Metadata}
+		jql := incrementalJQL(route.JQL, options.LastSynced, time.Local)
+		fetched, err := fetchAndSync(ctx, jira, dest, jql, mappingFields(mapping), routed, options, &report, notion.log())
+		if err != nil {
+			return report, err
+		}
+
+		if options.Stale != StaleIgnore {
+			routeOptions := options
+			routeOptions.JQL = route.JQL
+			stale, err := handleStalePages(ctx, jira, notion, route.DatabaseID, routeOptions, fetched)
+			report.Stale += stale
+			if err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if options.State != nil && len(report.Failed) == 0 {
+		if err := options.State.Clear(); err != nil {
+			return report, err
+		}
+	}
+
+	notion.log().Info("sync finished", "created", report.Created, "updated", report.Updated, "skipped", report.Skipped, "failed", len(report.Failed), "stale", report.Stale)
+	return report, nil
+}
+
+// SyncIssue creates or updates the Notion page for a single issue, as a
+// webhook-triggered run does. Unlike SyncJiraToNotion it returns the write
+// error directly. Only options.Metadata is used.
+func SyncIssue(ctx context.Context, jira *JiraClient, notion *NotionClient, mapping []FieldMapping, databaseID, issueKey string, opts ...SyncOptions) error {
+	var options SyncOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	issue, err := jira.FetchIssue(ctx, issueKey, mappingFields(mapping)...)
+	if err != nil {
+		return err
+	}
+
+	dest := &NotionSyncer{Client: notion, Mapping: mapping, DatabaseID: databaseID, Jira: jira, Metadata: options.Metadata}
+	_, err = dest.UpsertIssue(ctx, issue)
+	return err
+}
+
+// drainContext returns a context for finishing one unit of work: it isn't
+// cancelled with parent, but only grace after it, so a write that was under
+// way on shutdown can complete. Call done once the work is finished.
+func drainContext(parent context.Context, grace time.Duration) (ctx context.Context, done context.CancelFunc) {
+	if grace <= 0 {
+		grace = defaultShutdownTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	stop := context.AfterFunc(parent, func() {
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel()
+		case <-ctx.Done():
+		}
+	})
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// issueProperties builds the page properties for issue, including its keys,
+// Jira link and relations but not its LastSyncedProperty. It returns the
+// keys of related issues left out because they have no page yet. With
+// StaleMark the page's Stale flag is cleared, since its issue is back in
+// scope.
+func (s *NotionSyncer) issueProperties(ctx context.Context, issue Issue) (properties map[string]interface{}, missing []string, err error) {
+	properties, err = buildNotionProperties(issue, s.Mapping, s.Client.log())
+	if err != nil {
+		return nil, nil, err
+	}
+	s.Client.addIssueKeys(properties, issue, s.Mapping)
+	s.Metadata.addJiraURL(properties, issue)
+	if s.Stale == StaleMark {
+		properties[staleProperty] = map[string]interface{}{"checkbox": false}
+	}
+
+	relations, missing, err := resolveRelations(ctx, s.Client, s.DatabaseID, issue, s.Mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, value := range relations {
+		properties[name] = value
+	}
+
+	return properties, missing, nil
+}
+
+// propertiesHash fingerprints what would be written for an issue, so a run
+// can tell when nothing has changed since the last write.
+func propertiesHash(databaseID string, properties map[string]interface{}, page PageOptions) (string, error) {
+	// encoding/json sorts map keys, so equal properties marshal identically.
+	data, err := json.Marshal(struct {
+		Properties map[string]interface{}
+		Page       PageOptions
+	}{properties, page})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(append([]byte(databaseID+"\n"), data...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// writeIssuePage writes properties and page to the page for issue, or
+// creates one. It reports whether a page was created.
+func writeIssuePage(ctx context.Context, notion *NotionClient, issue Issue, properties map[string]interface{}, page PageOptions, databaseID string) (bool, error) {
+	pageID, created, err := notion.FindOrCreatePage(ctx, databaseID, issue.Key, issue.ID, properties, page)
+	if err != nil || created {
+		return created, err
+	}
+
+	return false, notion.UpdatePage(ctx, pageID, properties, page)
+}
+
+// linkDeferredRelations retries the relations issueProperties couldn't
+// resolve, once a related page has been written.
+func linkDeferredRelations(ctx context.Context, notion *NotionClient, issue Issue, mapping []FieldMapping, databaseID string) error {
+	relations, _, err := resolveRelations(ctx, notion, databaseID, issue, mapping)
+	if err != nil {
+		return err
+	}
+	if len(relations) == 0 {
+		return nil
+	}
+
+	pageID, err := notion.FindIssuePage(ctx, databaseID, issue.Key, issue.ID)
+	if err != nil || pageID == "" {
+		return err
+	}
+	return notion.UpdatePage(ctx, pageID, relations)
+}
@@ -0,0 +1,648 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNotion is an in-memory Notion database keyed by Jira key, served over
+// httptest. Creating a page whose key is in failKeys returns 400.
+type fakeNotion struct {
+	mu       sync.Mutex
+	pages    map[string]string
+	failKeys map[string]bool
+	// blips is how many creates should go through but answer 502, as when
+	// the response is lost on the way back.
+	blips   int
+	creates int
+	updates int
+	// relations records the related page ids written to each page.
+	relations map[string][]string
+	// archived counts pages moved to the trash, and stale records the last
+	// Stale checkbox written to each page.
+	archived int
+	stale    map[string]bool
+	// onCreate, when set, is called with each created page's key before the
+	// response is sent.
+	onCreate func(key string)
+}
+
+func (f *fakeNotion) recordRelations(pageID string, properties map[string]fakeProperty) {
+	for _, property := range properties {
+		for _, related := range property.Relation {
+			if f.relations == nil {
+				f.relations = map[string][]string{}
+			}
+			f.relations[pageID] = append(f.relations[pageID], related.ID)
+		}
+	}
+	if checkbox := properties[staleProperty].Checkbox; checkbox != nil {
+		if f.stale == nil {
+			f.stale = map[string]bool{}
+		}
+		f.stale[pageID] = *checkbox
+	}
+}
+
+type fakeProperty struct {
+	RichText []struct {
+		Text struct {
+			Content string `json:"content"`
+		} `json:"text"`
+	} `json:"rich_text"`
+	Relation []struct {
+		ID string `json:"id"`
+	} `json:"relation"`
+	Checkbox *bool `json:"checkbox"`
+}
+
+func (f *fakeNotion) handler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		var body struct {
+			Filter struct {
+				RichText struct {
+					Equals string `json:"equals"`
+				} `json:"rich_text"`
+			} `json:"filter"`
+			Properties map[string]fakeProperty `json:"properties"`
+			Archived   bool                    `json:"archived"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding request: %v", err)
+		}
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/query"):
+			results := []map[string]interface{}{}
+			if key := body.Filter.RichText.Equals; key != "" {
+				if id, ok := f.pages[key]; ok {
+					results = append(results, map[string]interface{}{"id": id})
+				}
+			} else {
+				// An unfiltered query lists every page, as handleStalePages makes.
+				for key, id := range f.pages {
+					results = append(results, map[string]interface{}{"id": id, "properties": map[string]interface{}{
+						defaultJiraKeyProperty: map[string]interface{}{"rich_text": []map[string]string{{"plain_text": key}}},
+					}})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"results": results, "has_more": false})
+		case r.Method == "POST" && r.URL.Path == "/pages":
+			key := ""
+			if rt := body.Properties[defaultJiraKeyProperty].RichText; len(rt) > 0 {
+				key = rt[0].Text.Content
+			}
+			if f.failKeys[key] {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f.creates++
+			id := "page-" + key
+			f.pages[key] = id
+			f.recordRelations(id, body.Properties)
+			if f.onCreate != nil {
+				f.onCreate(key)
+			}
+			if f.blips > 0 {
+				f.blips--
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"id": id})
+		case r.Method == "PATCH" && body.Archived:
+			f.archived++
+			for key, id := range f.pages {
+				if "/pages/"+id == r.URL.Path {
+					delete(f.pages, key)
+				}
+			}
+			w.WriteHeader(http.StatusOK)
+		case r.Method == "PATCH":
+			f.updates++
+			f.recordRelations(strings.TrimPrefix(r.URL.Path, "/pages/"), body.Properties)
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+}
+
+func jiraSearchServer(t *testing.T, issues []Issue) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := IssueResponse{Total: len(issues), Issues: issues}
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+}
+
+func TestSyncJiraToNotion(t *testing.T) {
+	jiraServer := jiraSearchServer(t, []Issue{
+		{Key: "TU-1", Fields: IssueFields{Summary: "Existing bet"}},
+		{Key: "TU-2", Fields: IssueFields{Summary: "New bet"}},
+		{Key: "TU-3", Fields: IssueFields{Summary: "Broken bet"}},
+	})
+	defer jiraServer.Close()
+
+	notion := &fakeNotion{pages: map[string]string{"TU-1": "page-TU-1"}, failKeys: map[string]bool{"TU-3": true}}
+	notionServer := httptest.NewServer(notion.handler(t))
+	defer notionServer.Close()
+
+	jira := newTestJiraClient(t, jiraServer.URL)
+
+	report, err := SyncJiraToNotion(context.Background(), jira, newTestNotionClient(notionServer.URL), defaultFieldMapping, "db-1")
+	if err != nil {
+		t.Fatalf("Error syncing: %v", err)
+	}
+
+	if report.Created != 1 || report.Updated != 1 {
+		t.Errorf("Expected 1 created and 1 updated, got %+v", report)
+	}
+
+	if len(report.Failed) != 1 || report.Failed[0].Key != "TU-3" {
+		t.Errorf("Expected TU-3 to fail, got %v", report.Failed)
+	}
+
+	if notion.pages["TU-2"] != "page-TU-2" {
+		t.Errorf("Expected a page to be created for TU-2, got %v", notion.pages)
+	}
+}
+
+func TestSyncRoutes(t *testing.T) {
+	jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var issues []Issue
+		switch r.URL.Query().Get("jql") {
+		case "project = TU":
+			issues = []Issue{{Key: "TU-1"}, {Key: "TU-2"}}
+		case "project = OPS":
+			issues = []Issue{{Key: "OPS-1"}}
+		case "project in (TU, OPS)":
+			issues = []Issue{{Key: "TU-1"}, {Key: "OPS-1"}, {Key: "OPS-2"}}
+		}
+		if err := json.NewEncoder(w).Encode(IssueResponse{Total: len(issues), Issues: issues}); err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+	defer jiraServer.Close()
+
+	var mu sync.Mutex
+	databases := map[string][]string{}
+	notionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/query") {
+			w.Write([]byte(`{"results": []}`))
+			return
+		}
+
+		var body struct {
+			Parent struct {
+				DatabaseID string `json:"database_id"`
+			} `json:"parent"`
+			Properties map[string]struct {
+				RichText []struct {
+					Text struct {
+						Content string `json:"content"`
+					} `json:"text"`
+				} `json:"rich_text"`
+			} `json:"properties"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding request: %v", err)
+		}
+
+		mu.Lock()
+		databases[body.Parent.DatabaseID] = append(databases[body.Parent.DatabaseID], body.Properties[defaultJiraKeyProperty].RichText[0].Text.Content)
+		mu.Unlock()
+		w.Write([]byte(`{"id": "page"}`))
+	}))
+	defer notionServer.Close()
+
+	routes := []Route{
+		ProjectRoute("TU", "db-tu"),
+		ProjectRoute("OPS", "db-ops"),
+		{JQL: "project in (TU, OPS)", DatabaseID: "db-all"},
+	}
+
+	var progress []Progress
+	options := SyncOptions{Progress: func(p Progress) { progress = append(progress, p) }}
+	report, err := SyncRoutes(context.Background(), newTestJiraClient(t, jiraServer.URL), newTestNotionClient(notionServer.URL), defaultFieldMapping, routes, options)
+	if err != nil {
+		t.Fatalf("Error syncing: %v", err)
+	}
+
+	if report.Created != 4 {
+		t.Errorf("Expected 4 pages created, got %d", report.Created)
+	}
+	// TU-1 and OPS-1 are matched twice but only synced once.
+	if len(progress) != 1 || progress[0] != (Progress{Processed: 4, Total: 4}) {
+		t.Errorf("Expected one final progress of 4 of 4, got %+v", progress)
+	}
+
+	expected := map[string][]string{
+		"db-tu":  {"TU-1", "TU-2"},
+		"db-ops": {"OPS-1"},
+		"db-all": {"OPS-2"},
+	}
+	for db, keys := range expected {
+		if strings.Join(databases[db], ",") != strings.Join(keys, ",") {
+			t.Errorf("Expected %s to receive %v, got %v", db, keys, databases[db])
+		}
+	}
+}
+
+func TestSyncLinksChildrenToEpics(t *testing.T) {
+	issues := []Issue{
+		// The child comes first, so its epic has no page yet when it is
+		// written, and the legacy field names the epic rather than parent.
+		{Key: "TU-2", Fields: IssueFields{Custom: map[string]json.RawMessage{EpicLinkFieldID: json.RawMessage(`"TU-1"`)}}},
+		{Key: "TU-1"},
+		{Key: "TU-3", Fields: IssueFields{Parent: &IssueParent{Key: "OPS-9"}}},
+	}
+	jiraServer := jiraSearchServer(t, issues)
+	defer jiraServer.Close()
+
+	notion := &fakeNotion{pages: map[string]string{}}
+	notionServer := httptest.NewServer(notion.handler(t))
+	defer notionServer.Close()
+
+	mapping := []FieldMapping{
+		{JiraField: "key", NotionProperty: defaultJiraKeyProperty, NotionType: NotionRichText},
+		{JiraField: "epic", NotionProperty: "Epic", NotionType: NotionRelation},
+	}
+	report, err := SyncJiraToNotion(context.Background(), newTestJiraClient(t, jiraServer.URL), newTestNotionClient(notionServer.URL), mapping, "db-1")
+	if err != nil {
+		t.Fatalf("Error syncing: %v", err)
+	}
+
+	if report.Created != 3 || len(report.Failed) != 0 {
+		t.Errorf("Expected 3 pages created, got %+v", report)
+	}
+
+	if got := notion.relations["page-TU-2"]; len(got) != 1 || got[0] != "page-TU-1" {
+		t.Errorf("Expected TU-2 to link to its epic's page, got %v", got)
+	}
+
+	// OPS-9 isn't in scope, so TU-3 is left unlinked rather than failing.
+	if got := notion.relations["page-TU-3"]; len(got) != 0 {
+		t.Errorf("Expected the orphaned TU-3 to have no relation, got %v", got)
+	}
+}
+
+func TestSyncLinksChildrenToEpicsInLaterBatches(t *testing.T) {
+	// One issue per batch, with the epic in the batch after its child.
+	issues := []Issue{
+		{Key: "TU-2", Fields: IssueFields{Parent: &IssueParent{Key: "TU-1"}}},
+		{Key: "TU-1"},
+	}
+	jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		response := IssueResponse{StartAt: startAt, MaxResults: 1, Total: len(issues), Issues: issues[startAt : startAt+1]}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+	defer jiraServer.Close()
+
+	notion := &fakeNotion{pages: map[string]string{}}
+	notionServer := httptest.NewServer(notion.handler(t))
+	defer notionServer.Close()
+
+	mapping := []FieldMapping{{JiraField: "parent", NotionProperty: "Parent", NotionType: NotionRelation}}
+	report, err := SyncJiraToNotion(context.Background(), newTestJiraClient(t, jiraServer.URL), newTestNotionClient(notionServer.URL), mapping, "db-1", SyncOptions{BatchSize: 1})
+	if err != nil {
+		t.Fatalf("Error syncing: %v", err)
+	}
+
+	if report.Created != 2 || len(report.Failed) != 0 {
+		t.Errorf("Expected 2 pages created, got %+v", report)
+	}
+
+	if got := notion.relations["page-TU-2"]; len(got) != 1 || got[0] != "page-TU-1" {
+		t.Errorf("Expected TU-2 to link to its parent's page from the next batch, got %v", got)
+	}
+}
+
+func TestSyncRecordsIssuesLinkedOutsideScope(t *testing.T) {
+	// OPS-9 is outside the JQL, so TU-3's relation can never be set.
+	jiraServer := jiraSearchServer(t, []Issue{{Key: "TU-3", Fields: IssueFields{Parent: &IssueParent{Key: "OPS-9"}}}})
+	defer jiraServer.Close()
+
+	notion := &fakeNotion{pages: map[string]string{}}
+	notionServer := httptest.NewServer(notion.handler(t))
+	defer notionServer.Close()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	mapping := []FieldMapping{{JiraField: "parent", NotionProperty: "Parent", NotionType: NotionRelation}}
+	var reports []SyncReport
+	for run := 0; run < 2; run++ {
+		state, err := LoadSyncState(path, nil)
+		if err != nil {
+			t.Fatalf("Error loading state: %v", err)
+		}
+		report, err := SyncJiraToNotion(context.Background(), newTestJiraClient(t, jiraServer.URL), newTestNotionClient(notionServer.URL), mapping, "db-1", SyncOptions{State: state})
+		if err != nil {
+			t.Fatalf("Error syncing: %v", err)
+		}
+		reports = append(reports, report)
+	}
+
+	if reports[0].Created != 1 || reports[1].Skipped != 1 {
+		t.Errorf("Expected TU-3 to be created, then skipped as unchanged, got %+v", reports)
+	}
+	if notion.updates != 0 {
+		t.Errorf("Expected no attempt to link the missing OPS-9, got %d updates", notion.updates)
+	}
+}
+
+func TestSyncReportCounts(t *testing.T) {
+	jiraServer := jiraSearchServer(t, []Issue{{Key: "TU-1"}, {Key: "TU-2"}, {Key: "TU-3"}, {Key: "TU-4"}})
+	defer jiraServer.Close()
+
+	notion := &fakeNotion{pages: map[string]string{"TU-1": "page-TU-1"}, failKeys: map[string]bool{"TU-3": true}}
+	notionServer := httptest.NewServer(notion.handler(t))
+	defer notionServer.Close()
+
+	state, err := LoadSyncState(filepath.Join(t.TempDir(), "state.json"), nil)
+	if err != nil {
+		t.Fatalf("Error loading state: %v", err)
+	}
+	if err := state.MarkDone("TU-4"); err != nil {
+		t.Fatalf("Error marking TU-4 done: %v", err)
+	}
+
+	report, err := SyncJiraToNotion(context.Background(), newTestJiraClient(t, jiraServer.URL), newTestNotionClient(notionServer.URL), defaultFieldMapping, "db-1", SyncOptions{State: state})
+	if err != nil {
+		t.Fatalf("Error syncing: %v", err)
+	}
+
+	if report.Created != 1 || report.Updated != 1 || report.Skipped != 1 {
+		t.Errorf("Expected 1 created, 1 updated and 1 skipped, got %+v", report)
+	}
+
+	if len(report.Failed) != 1 || report.Failed[0].Key != "TU-3" || !strings.Contains(report.Failed[0].Error, "Status: 400") {
+		t.Errorf("Expected TU-3 to fail with its HTTP error, got %+v", report.Failed)
+	}
+
+	if report.Duration <= 0 {
+		t.Errorf("Expected the duration to be recorded, got %v", report.Duration)
+	}
+
+	if got := report.String(); !strings.HasPrefix(got, "created 1, updated 1, skipped 1, failed 1, stale 0 in ") {
+		t.Errorf("Expected a one-line summary, got %q", got)
+	}
+}
+
+func TestSyncRetriedCreateMakesOnePage(t *testing.T) {
+	jiraServer := jiraSearchServer(t, []Issue{{Key: "TU-1"}})
+	defer jiraServer.Close()
+
+	notion := &fakeNotion{pages: map[string]string{}, blips: 1}
+	notionServer := httptest.NewServer(notion.handler(t))
+	defer notionServer.Close()
+
+	client := newTestNotionClient(notionServer.URL)
+	client.RetryBaseDelay = time.Millisecond
+
+	report, err := SyncJiraToNotion(context.Background(), newTestJiraClient(t, jiraServer.URL), client, defaultFieldMapping, "db-1")
+	if err != nil {
+		t.Fatalf("Error syncing: %v", err)
+	}
+
+	if notion.creates != 1 || len(notion.pages) != 1 {
+		t.Errorf("Expected one page to be created, got %d creates and pages %v", notion.creates, notion.pages)
+	}
+
+	if report.Created != 1 || notion.updates != 0 || len(report.Failed) != 0 {
+		t.Errorf("Expected the retried create to count once, got %+v with %d updates", report, notion.updates)
+	}
+}
+
+func TestSyncSkipsUnchangedIssues(t *testing.T) {
+	issues := []Issue{
+		{Key: "TU-1", Fields: IssueFields{Summary: "Stable bet"}},
+		{Key: "TU-2", Fields: IssueFields{Summary: "Changing bet"}},
+	}
+	jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewEncoder(w).Encode(IssueResponse{Total: len(issues), Issues: issues}); err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+	defer jiraServer.Close()
+
+	notion := &fakeNotion{pages: map[string]string{}}
+	notionServer := httptest.NewServer(notion.handler(t))
+	defer notionServer.Close()
+
+	// Each write is stamped with a later time, which isn't a change.
+	stamped := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	metadata := PageMetadata{LastSyncedProperty: "Last Synced", Now: func() time.Time {
+		stamped = stamped.Add(time.Hour)
+		return stamped
+	}}
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	runSync := func() SyncReport {
+		t.Helper()
+
+		state, err := LoadSyncState(path, nil)
+		if err != nil {
+			t.Fatalf("Error loading state: %v", err)
+		}
+		report, err := SyncJiraToNotion(context.Background(), newTestJiraClient(t, jiraServer.URL), newTestNotionClient(notionServer.URL), defaultFieldMapping, "db-1", SyncOptions{State: state, Metadata: metadata})
+		if err != nil {
+			t.Fatalf("Error syncing: %v", err)
+		}
+		return report
+	}
+
+	if report := runSync(); report.Created != 2 {
+		t.Fatalf("Expected the first run to create 2 pages, got %+v", report)
+	}
+
+	issues[1].Fields.Summary = "Changed bet"
+	report := runSync()
+
+	if report.Updated != 1 || report.Skipped != 1 {
+		t.Errorf("Expected 1 updated and 1 skipped, got %+v", report)
+	}
+
+	if notion.updates != 1 {
+		t.Errorf("Expected 1 page update, got %d", notion.updates)
+	}
+}
+
+func TestSyncInterruptedKeepsState(t *testing.T) {
+	jiraServer := jiraSearchServer(t, []Issue{{Key: "TU-1"}, {Key: "TU-2"}, {Key: "TU-3"}})
+	defer jiraServer.Close()
+
+	// The signal arrives while TU-2 is being written.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	notion := &fakeNotion{pages: map[string]string{}, onCreate: func(key string) {
+		if key == "TU-2" {
+			cancel()
+		}
+	}}
+	notionServer := httptest.NewServer(notion.handler(t))
+	defer notionServer.Close()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := LoadSyncState(path, nil)
+	if err != nil {
+		t.Fatalf("Error loading state: %v", err)
+	}
+
+	var progress []Progress
+	options := SyncOptions{State: state, Progress: func(p Progress) { progress = append(progress, p) }}
+	report, err := SyncJiraToNotion(ctx, newTestJiraClient(t, jiraServer.URL), newTestNotionClient(notionServer.URL), defaultFieldMapping, "db-1", options)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the run to report the cancellation, got %v", err)
+	}
+
+	if report.Created != 2 || len(report.Failed) != 0 {
+		t.Errorf("Expected the in-flight write to finish, got %+v", report)
+	}
+	if len(progress) != 1 || progress[0] != (Progress{Processed: 2, Total: 3}) {
+		t.Errorf("Expected a final progress of 2 of 3, got %+v", progress)
+	}
+	if _, ok := notion.pages["TU-3"]; ok {
+		t.Errorf("Expected TU-3 not to be written after the interrupt")
+	}
+
+	reloaded, err := LoadSyncState(path, nil)
+	if err != nil {
+		t.Fatalf("Error reloading state: %v", err)
+	}
+	for key, done := range map[string]bool{"TU-1": true, "TU-2": true, "TU-3": false} {
+		if reloaded.IsDone(key) != done {
+			t.Errorf("Expected %s done to be %v in the saved state, got %v", key, done, !done)
+		}
+	}
+}
+
+func TestProjectsJQL(t *testing.T) {
+	jql, err := projectsJQL(" eng, OPS ")
+	if err != nil {
+		t.Fatalf("Error building JQL: %v", err)
+	}
+
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expected := `(project IN (ENG, OPS)) AND updated >= "2024-05-01 11:58"`
+	if got := incrementalJQL(jql, since, time.UTC); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	for _, keys := range []string{"", "ENG,,OPS", "ENG,1OPS", "ENG OR 1=1"} {
+		if _, err := projectsJQL(keys); err == nil {
+			t.Errorf("Expected an error for project keys %q", keys)
+		}
+	}
+}
+
+func TestSyncWritesJiraKeyWithoutMapping(t *testing.T) {
+	jiraServer := jiraSearchServer(t, []Issue{{Key: "TU-1", Fields: IssueFields{Summary: "Unkeyed bet"}}})
+	defer jiraServer.Close()
+
+	notion := &fakeNotion{pages: map[string]string{}}
+	notionServer := httptest.NewServer(notion.handler(t))
+	defer notionServer.Close()
+
+	mapping := []FieldMapping{{JiraField: "summary", NotionProperty: "Name", NotionType: NotionTitle}}
+	for run := 0; run < 2; run++ {
+		if _, err := SyncJiraToNotion(context.Background(), newTestJiraClient(t, jiraServer.URL), newTestNotionClient(notionServer.URL), mapping, "db-1"); err != nil {
+			t.Fatalf("Error syncing: %v", err)
+		}
+	}
+
+	if _, ok := notion.pages["TU-1"]; !ok || notion.creates != 1 {
+		t.Errorf("Expected one page created with its Jira key, got %d creates and pages %v", notion.creates, notion.pages)
+	}
+	if notion.updates != 1 {
+		t.Errorf("Expected the second run to find and update the page, got %d updates", notion.updates)
+	}
+}
+
+func TestSyncKeysPagesByInstance(t *testing.T) {
+	notion := &fakeNotion{pages: map[string]string{}}
+	notionServer := httptest.NewServer(notion.handler(t))
+	defer notionServer.Close()
+
+	// Each site syncs through its own client, so both can share db-1 at once.
+	syncers := map[string]*NotionSyncer{}
+	for _, instance := range []string{"alpha", "beta"} {
+		client := newTestNotionClient(notionServer.URL)
+		client.JiraInstance = instance
+		syncers[instance] = &NotionSyncer{Client: client, Mapping: defaultFieldMapping, DatabaseID: "db-1"}
+	}
+	run := func(instance string) {
+		jiraServer := jiraSearchServer(t, []Issue{{Key: "TU-1", Fields: IssueFields{Summary: instance + " bet"}}})
+		defer jiraServer.Close()
+		report, err := SyncTo(context.Background(), newTestJiraClient(t, jiraServer.URL), syncers[instance], SyncOptions{Fields: mappingFields(defaultFieldMapping)})
+		if err != nil || len(report.Failed) != 0 {
+			t.Errorf("Error syncing %s: %v, %+v", instance, err, report.Failed)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for instance := range syncers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run(instance)
+		}()
+	}
+	wg.Wait()
+	run("alpha")
+
+	if notion.creates != 2 || notion.pages["alpha:TU-1"] == "" || notion.pages["beta:TU-1"] == "" {
+		t.Errorf("Expected a page each for alpha:TU-1 and beta:TU-1, got %d creates and pages %v", notion.creates, notion.pages)
+	}
+	if notion.updates != 1 {
+		t.Errorf("Expected the second alpha run to update its own page, got %d updates", notion.updates)
+	}
+}
+
+func TestSyncFindsMovedIssueByID(t *testing.T) {
+	// TU-1 moved to OPS and is now OPS-1; its page holds the old key.
+	jiraServer := jiraSearchServer(t, []Issue{{ID: "10001", Key: "OPS-1", Fields: IssueFields{Summary: "Moved bet"}}})
+	defer jiraServer.Close()
+
+	server := newNotionTestServer(t)
+	server.Query = &notionResponse{Body: `{"results": [{"id": "page-TU-1"}], "has_more": false}`}
+
+	notion := server.NotionClient()
+	notion.JiraIDProperty = "Jira ID"
+	mapping := []FieldMapping{{JiraField: "summary", NotionProperty: "Name", NotionType: NotionTitle}}
+	report, err := SyncJiraToNotion(context.Background(), newTestJiraClient(t, jiraServer.URL), notion, mapping, "db-1")
+	if err != nil {
+		t.Fatalf("Error syncing: %v", err)
+	}
+	if report.Created != 0 || report.Updated != 1 {
+		t.Errorf("Expected the existing page to be updated, got %+v", report)
+	}
+
+	query, _ := server.LastRequest("POST", "/databases/db-1/query")
+	assertJSON(t, query.Body["filter"], `{"property": "Jira ID", "rich_text": {"equals": "10001"}}`)
+
+	update, ok := server.LastRequest("PATCH", "/pages/page-TU-1")
+	if !ok {
+		t.Fatalf("Expected page-TU-1 to be updated, got %+v", server.Requests())
+	}
+	written, _ := update.Body["properties"].(map[string]interface{})
+	assertJSON(t, written["Jira Key"], `{"rich_text": [{"type": "text", "text": {"content": "OPS-1"}}]}`)
+	assertJSON(t, written["Jira ID"], `{"rich_text": [{"type": "text", "text": {"content": "10001"}}]}`)
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Syncer writes issues to a sync destination. NotionSyncer is the Notion
+// one; another backend, such as a CSV file, only has to implement
+// UpsertIssue to be driven by SyncTo.
+type Syncer interface {
+	// UpsertIssue creates or updates the destination's copy of issue.
+	UpsertIssue(ctx context.Context, issue Issue) (UpsertResult, error)
+}
+
+// UpsertResult is what an UpsertIssue call did.
+type UpsertResult struct {
+	Created bool
+	// Unchanged means the destination already matched issue, so nothing was
+	// written.
+	Unchanged bool
+	// Hash fingerprints what was written, for SyncState. "" still marks the
+	// issue done, but it is written again by the next run.
+	Hash string
+}
+
+// finisher is implemented by Syncers with work left once every issue of a
+// run has been upserted, such as NotionSyncer's deferred relations.
+type finisher interface {
+	finish(ctx context.Context)
+}
+
+// SyncTo fetches the issues matching options.JQL, with options.Fields, and
+// upserts each into dest. Failures, state and cancellation are handled as by
+// SyncRoutes; options.Stale is ignored, since it depends on the destination.
+func SyncTo(ctx context.Context, jira *JiraClient, dest Syncer, opts ...SyncOptions) (report SyncReport, err error) {
+	var options SyncOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	start := time.Now()
+	report = SyncReport{Watermark: options.LastSynced}
+	defer func() {
+		report.Duration = time.Since(start)
+		reportProgress(options, &report, true)
+	}()
+
+	jql := incrementalJQL(options.JQL, options.LastSynced, time.Local)
+	if _, err := fetchAndSync(ctx, jira, dest, jql, options.Fields, map[string]bool{}, options, &report, jira.log()); err != nil {
+		return report, err
+	}
+
+	if options.State != nil && len(report.Failed) == 0 {
+		if err := options.State.Clear(); err != nil {
+			return report, err
+		}
+	}
+
+	jira.log().Info("sync finished", "created", report.Created, "updated", report.Updated, "skipped", report.Skipped, "failed", len(report.Failed))
+	return report, nil
+}
+
+// fetchAndSync fetches the issues matching jql, in batches of
+// options.BatchSize if set, upserts them into dest with syncIssues, and then
+// finishes dest if it is a finisher. It returns the fetched issues, or nil
+// when the run resumed from a saved cursor and so didn't see them all.
+func fetchAndSync(ctx context.Context, jira *JiraClient, dest Syncer, jql string, fields []string, routed map[string]bool, options SyncOptions, report *SyncReport, logger *slog.Logger) ([]Issue, error) {
+	fetch := FetchOptions{Fields: fields, Limit: options.BatchSize}
+	saveCursor := options.BatchSize > 0 && options.State != nil
+	if saveCursor {
+		fetch.Cursor = options.State.Cursor(jql)
+	}
+	resumed := fetch.Cursor != ""
+	if resumed {
+		logger.Info("resuming search from saved cursor", "jql", jql, "cursor", fetch.Cursor)
+	}
+
+	var fetched []Issue
+	failed := len(report.Failed)
+	searched := report.total
+	duplicates := 0
+	for {
+		result, err := jira.FetchIssues(ctx, jql, fetch)
+		if err != nil {
+			return nil, err
+		}
+		// Issues on the pages skipped by a resumed run may be newer, so
+		// its watermark stays put and the next run covers them again.
+		if !resumed {
+			report.Watermark = highWatermark(result.Issues, report.Watermark)
+		}
+		fetched = append(fetched, result.Issues...)
+		// Issues an earlier route synced are skipped, so they don't count.
+		for _, issue := range result.Issues {
+			if routed[issue.Key] {
+				duplicates++
+			}
+		}
+		// The v2 search includes its total with each batch; v3 only tells
+		// issues as they come.
+		if result.Total > 0 {
+			report.total = searched + result.Total - duplicates
+		} else {
+			report.total = searched + len(fetched) - duplicates
+		}
+
+		if err := syncIssues(ctx, dest, result.Issues, routed, options, report, logger); err != nil {
+			return nil, err
+		}
+		if options.BatchSize <= 0 || result.Cursor == "" {
+			break
+		}
+
+		fetch.Cursor = result.Cursor
+		// Past a failure the cursor stays behind it, so a resumed run
+		// retries the failed issue.
+		if saveCursor && len(report.Failed) == failed {
+			if err := options.State.SetCursor(jql, result.Cursor); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Only once every batch is written, as a related issue may come in a
+	// later one.
+	if f, ok := dest.(finisher); ok {
+		f.finish(ctx)
+	}
+
+	if resumed {
+		return nil, nil
+	}
+	return fetched, nil
+}
+
+// syncIssues upserts each issue not yet in routed into dest, tallying the
+// outcome in report. It only returns an error for a failed state write or a
+// cancelled ctx; see SyncRoutes.
+func syncIssues(ctx context.Context, dest Syncer, issues []Issue, routed map[string]bool, options SyncOptions, report *SyncReport, logger *slog.Logger) error {
+	for _, issue := range issues {
+		if routed[issue.Key] {
+			continue
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("sync interrupted: %w", ctx.Err())
+		}
+		routed[issue.Key] = true
+
+		if err := syncIssue(ctx, dest, issue, options, report, logger); err != nil {
+			return err
+		}
+		reportProgress(options, report, false)
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("sync interrupted: %w", ctx.Err())
+	}
+	return nil
+}
+
+// syncIssue upserts one issue for syncIssues and tallies it in report.
+func syncIssue(ctx context.Context, dest Syncer, issue Issue, options SyncOptions, report *SyncReport, logger *slog.Logger) error {
+	if options.State != nil && options.State.IsDone(issue.Key) {
+		report.Skipped++
+		return nil
+	}
+
+	result, err := upsertWithTimeout(ctx, dest, issue, options)
+	if err != nil {
+		logger.Error("syncing issue failed", "issueKey", issue.Key, "error", err)
+		report.Failed = append(report.Failed, FailedItem{Key: issue.Key, Error: err.Error()})
+		return nil
+	}
+
+	switch {
+	case result.Unchanged:
+		report.Skipped++
+		return nil
+	case result.Created:
+		report.Created++
+	default:
+		report.Updated++
+	}
+
+	if options.State != nil {
+		return options.State.MarkSynced(issue.Key, result.Hash)
+	}
+	return nil
+}
+
+// upsertWithTimeout upserts issue within options.IssueTimeout, letting it
+// finish if ctx is cancelled meanwhile; see drainContext.
+func upsertWithTimeout(ctx context.Context, dest Syncer, issue Issue, options SyncOptions) (UpsertResult, error) {
+	work, done := drainContext(ctx, options.ShutdownTimeout)
+	defer done()
+
+	timeout := options.IssueTimeout
+	if timeout == 0 {
+		timeout = defaultIssueTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		work, cancel = context.WithTimeout(work, timeout)
+		defer cancel()
+	}
+
+	result, err := dest.UpsertIssue(work, issue)
+	if err != nil && errors.Is(work.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s: %w", timeout, err)
+	}
+	return result, err
+}
+
+// NotionSyncer is the Syncer for one Notion database, writing each issue's
+// page as buildNotionProperties and resolveRelations describe it.
+type NotionSyncer struct {
+	Client     *NotionClient
+	Mapping    []FieldMapping
+	DatabaseID string
+	// Jira, when set, fetches the watchers and voters of mappings that need
+	// them; see userListProperties.
+	Jira *JiraClient
+	// Stale, when StaleMark, clears the page's Stale flag on each write.
+	Stale StaleAction
+	// State, when set, supplies the hash of each issue's last write, so
+	// unchanged issues are reported as such rather than written again.
+	State *SyncState
+	// Metadata adds a link back to Jira and a last-synced date to each page.
+	Metadata PageMetadata
+
+	// deferred are issues whose related pages didn't exist when they were
+	// written; finish links them to the ones written since, as recorded in
+	// written.
+	deferred []deferredIssue
+	written  map[string]bool
+}
+
+// deferredIssue is an issue whose relations left out the related issues in
+// missing, which had no page.
+type deferredIssue struct {
+	issue   Issue
+	missing []string
+}
+
+// UpsertIssue creates or updates the page for issue.
+func (s *NotionSyncer) UpsertIssue(ctx context.Context, issue Issue) (UpsertResult, error) {
+	properties, missing, err := s.issueProperties(ctx, issue)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	if s.Jira != nil {
+		users, err := userListProperties(ctx, s.Jira, issue, s.Mapping)
+		if err != nil {
+			return UpsertResult{}, err
+		}
+		for name, value := range users {
+			properties[name] = value
+		}
+	}
+
+	hash, err := propertiesHash(s.DatabaseID, properties, issuePageOptions(issue))
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	// The related page may still be written later in the run, so the issue
+	// waits for it even when unchanged. Once that page exists the relation
+	// changes the hash, so a related issue that never gets a page, such as
+	// an epic outside the JQL, doesn't make the issue look changed.
+	if len(missing) > 0 {
+		s.deferred = append(s.deferred, deferredIssue{issue: issue, missing: missing})
+	}
+	if s.State != nil && s.State.Hash(issue.Key) == hash {
+		return UpsertResult{Unchanged: true, Hash: hash}, nil
+	}
+	// The date changes on every write, so it is stamped after hashing.
+	s.Metadata.stampLastSynced(properties)
+
+	created, err := writeIssuePage(ctx, s.Client, issue, properties, issuePageOptions(issue), s.DatabaseID)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+
+	if s.written == nil {
+		s.written = map[string]bool{}
+	}
+	s.written[issue.Key] = true
+	return UpsertResult{Created: created, Hash: hash}, nil
+}
+
+// finish links the pages whose related issues were written after them.
+// Related issues the run didn't write, such as ones outside the JQL, are
+// skipped.
+func (s *NotionSyncer) finish(ctx context.Context) {
+	for _, d := range s.deferred {
+		if !slices.ContainsFunc(d.missing, func(key string) bool { return s.written[key] }) {
+			s.Client.log().Debug("related issue has no Notion page, e.g. an epic outside the JQL; relation skipped", "issueKey", d.issue.Key, "related", d.missing)
+			continue
+		}
+		if err := linkDeferredRelations(ctx, s.Client, d.issue, s.Mapping, s.DatabaseID); err != nil {
+			s.Client.log().Error("linking related pages failed", "issueKey", d.issue.Key, "error", err)
+		}
+	}
+	s.deferred = nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSyncer records each upserted issue in memory. Keys in failKeys fail,
+// and keys already in issues are updated rather than created.
+type fakeSyncer struct {
+	issues   map[string]Issue
+	calls    []string
+	failKeys map[string]bool
+	// hangKeys block until ctx is done, like a request that never returns.
+	hangKeys map[string]bool
+}
+
+func (f *fakeSyncer) UpsertIssue(ctx context.Context, issue Issue) (UpsertResult, error) {
+	f.calls = append(f.calls, issue.Key)
+	if f.hangKeys[issue.Key] {
+		<-ctx.Done()
+		return UpsertResult{}, ctx.Err()
+	}
+	if f.failKeys[issue.Key] {
+		return UpsertResult{}, errors.New("destination rejected the issue")
+	}
+
+	_, exists := f.issues[issue.Key]
+	f.issues[issue.Key] = issue
+	return UpsertResult{Created: !exists, Hash: issue.Fields.Summary}, nil
+}
+
+func TestSyncToFakeSyncer(t *testing.T) {
+	jiraServer := jiraSearchServer(t, []Issue{
+		{Key: "TU-1", Fields: IssueFields{Summary: "Existing bet"}},
+		{Key: "TU-2", Fields: IssueFields{Summary: "New bet"}},
+		{Key: "TU-3", Fields: IssueFields{Summary: "Broken bet"}},
+	})
+	defer jiraServer.Close()
+
+	dest := &fakeSyncer{issues: map[string]Issue{"TU-1": {Key: "TU-1"}}, failKeys: map[string]bool{"TU-3": true}}
+
+	report, err := SyncTo(context.Background(), newTestJiraClient(t, jiraServer.URL), dest)
+	if err != nil {
+		t.Fatalf("Error syncing: %v", err)
+	}
+
+	if len(dest.calls) != 3 || dest.calls[0] != "TU-1" || dest.calls[1] != "TU-2" || dest.calls[2] != "TU-3" {
+		t.Errorf("Expected TU-1, TU-2 and TU-3 to be upserted in order, got %v", dest.calls)
+	}
+
+	if dest.issues["TU-2"].Fields.Summary != "New bet" {
+		t.Errorf("Expected TU-2 to be stored, got %+v", dest.issues["TU-2"])
+	}
+
+	if report.Created != 1 || report.Updated != 1 || len(report.Failed) != 1 || report.Failed[0].Key != "TU-3" {
+		t.Errorf("Expected 1 created, 1 updated and TU-3 failed, got %+v", report)
+	}
+}
+
+func TestSyncToIssueTimeout(t *testing.T) {
+	jiraServer := jiraSearchServer(t, []Issue{{Key: "TU-1"}, {Key: "TU-2"}, {Key: "TU-3"}})
+	defer jiraServer.Close()
+
+	dest := &fakeSyncer{issues: map[string]Issue{}, hangKeys: map[string]bool{"TU-2": true}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	report, err := SyncTo(ctx, newTestJiraClient(t, jiraServer.URL), dest, SyncOptions{IssueTimeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Error syncing: %v", err)
+	}
+
+	if len(report.Failed) != 1 || report.Failed[0].Key != "TU-2" || !strings.Contains(report.Failed[0].Error, "timed out") {
+		t.Errorf("Expected only TU-2 to time out, got %+v", report.Failed)
+	}
+
+	if report.Created != 2 {
+		t.Errorf("Expected the run to carry on and create TU-1 and TU-3, got %+v", report)
+	}
+}
+
+func TestSyncToProgress(t *testing.T) {
+	var issues []Issue
+	for i := 1; i <= 10; i++ {
+		issues = append(issues, Issue{Key: "TU-" + strconv.Itoa(i)})
+	}
+	jiraServer := jiraSearchServer(t, issues)
+	defer jiraServer.Close()
+
+	dest := &fakeSyncer{issues: map[string]Issue{}, failKeys: map[string]bool{"TU-5": true}}
+
+	var updates []Progress
+	options := SyncOptions{ProgressEvery: 3, Progress: func(p Progress) { updates = append(updates, p) }}
+	if _, err := SyncTo(context.Background(), newTestJiraClient(t, jiraServer.URL), dest, options); err != nil {
+		t.Fatalf("Error syncing: %v", err)
+	}
+
+	expected := []Progress{
+		{Processed: 3, Total: 10},
+		{Processed: 6, Total: 10, Failed: 1},
+		{Processed: 9, Total: 10, Failed: 1},
+		{Processed: 10, Total: 10, Failed: 1},
+	}
+	if !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected progress %+v, got %+v", expected, updates)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewTLSHTTPClientTrustsCAFile(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"total": 0, "issues": []}`))
+	}))
+	defer ts.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0o644); err != nil {
+		t.Fatalf("Error writing CA file: %v", err)
+	}
+
+	// Without the CA the server's certificate is rejected.
+	if _, err := fetchIssues(context.Background(), "encodedCredentials", ts.URL, ""); err == nil {
+		t.Fatal("Expected the default client to reject the test certificate")
+	}
+
+	httpClient, err := NewTLSHTTPClient(TLSOptions{CAFile: caFile}, nil)
+	if err != nil {
+		t.Fatalf("Error creating HTTP client: %v", err)
+	}
+
+	client, err := NewJiraClient(ts.URL, "encodedCredentials", httpClient)
+	if err != nil {
+		t.Fatalf("Error creating client: %v", err)
+	}
+	if _, err := client.FetchIssues(context.Background(), ""); err != nil {
+		t.Errorf("Expected the CA-configured client to connect, got %v", err)
+	}
+}
+
+func TestNewTLSHTTPClientInsecureSkipVerifyWarns(t *testing.T) {
+	var buf bytes.Buffer
+	httpClient, err := NewTLSHTTPClient(TLSOptions{InsecureSkipVerify: true}, slog.New(slog.NewTextHandler(&buf, nil)))
+	if err != nil {
+		t.Fatalf("Error creating HTTP client: %v", err)
+	}
+
+	if !httpClient.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify {
+		t.Error("Expected certificate verification to be disabled")
+	}
+
+	if !strings.Contains(buf.String(), "level=WARN") {
+		t.Errorf("Expected a warning, got %s", buf.String())
+	}
+}
+
+func TestNewTLSHTTPClientBadCAFile(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatalf("Error writing CA file: %v", err)
+	}
+
+	if _, err := NewTLSHTTPClient(TLSOptions{CAFile: caFile}, nil); err == nil {
+		t.Error("Expected an error for a bundle without certificates")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Transition is a workflow step available from an issue's current status.
+type Transition struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	To   Status `json:"to"`
+}
+
+type transitionsResponse struct {
+	Transitions []Transition `json:"transitions"`
+}
+
+// GetTransitions lists the transitions available from an issue's current
+// status.
+func (c *JiraClient) GetTransitions(ctx context.Context, issueKey string) ([]Transition, error) {
+	issueURL, err := c.issueURL(issueKey)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(ctx, "GET", issueURL+"/transitions", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpError(resp.StatusCode, readErrorBody(resp))
+	}
+
+	var body transitionsResponse
+	if err := c.decode(resp, &body); err != nil {
+		return nil, err
+	}
+
+	return body.Transitions, nil
+}
+
+// TransitionIssue moves an issue through its workflow. The transition is
+// checked against GetTransitions first so an invalid move is reported with
+// the transitions that are available instead of Jira's bare 400.
+func (c *JiraClient) TransitionIssue(ctx context.Context, issueKey, transitionID string) error {
+	transitions, err := c.GetTransitions(ctx, issueKey)
+	if err != nil {
+		return err
+	}
+
+	valid := false
+	for _, t := range transitions {
+		if t.ID == transitionID {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("transition %s is not available for %s from its current status (available: %s)", transitionID, issueKey, describeTransitions(transitions))
+	}
+
+	return c.doTransition(ctx, issueKey, transitionID)
+}
+
+// doTransition performs transitionID, which the caller has already checked
+// is available for issueKey.
+func (c *JiraClient) doTransition(ctx context.Context, issueKey, transitionID string) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"transition": map[string]string{"id": transitionID},
+	})
+	if err != nil {
+		return err
+	}
+
+	issueURL, err := c.issueURL(issueKey)
+	if err != nil {
+		return err
+	}
+	transitionsURL := issueURL + "/transitions"
+	if c.DryRun {
+		logDryRun(c.log(), "POST", transitionsURL, payload)
+		return nil
+	}
+
+	resp, err := c.do(ctx, "POST", transitionsURL, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := httpError(resp.StatusCode, readErrorBody(resp))
+		c.log().Error("transition failed", "issueKey", issueKey, "transitionID", transitionID, "statusCode", resp.StatusCode, "error", err)
+		return err
+	}
+
+	c.log().Info("transitioned issue", "issueKey", issueKey, "transitionID", transitionID, "statusCode", resp.StatusCode)
+	return nil
+}
+
+// TransitionToStatus moves an issue to the named status using whichever
+// available transition leads there. Status names are matched
+// case-insensitively.
+func (c *JiraClient) TransitionToStatus(ctx context.Context, issueKey, statusName string) error {
+	transitions, err := c.GetTransitions(ctx, issueKey)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range transitions {
+		if strings.EqualFold(t.To.Name, statusName) {
+			return c.doTransition(ctx, issueKey, t.ID)
+		}
+	}
+
+	return fmt.Errorf("no transition to status %q is available for %s (available: %s)", statusName, issueKey, describeTransitions(transitions))
+}
+
+func describeTransitions(transitions []Transition) string {
+	if len(transitions) == 0 {
+		return "none"
+	}
+
+	names := make([]string, len(transitions))
+	for i, t := range transitions {
+		names[i] = fmt.Sprintf("%s %q -> %s", t.ID, t.Name, t.To.Name)
+	}
+	return strings.Join(names, ", ")
+}
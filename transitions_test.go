@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// transitionsServer lists two transitions for TU-1, appending the id of each
+// one executed to executed and counting the lists in lists.
+func transitionsServer(t *testing.T, executed *[]string, lists *int) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/issue/TU-1/transitions" {
+			t.Errorf("Expected path to be %s, got %s", "/rest/api/2/issue/TU-1/transitions", r.URL.Path)
+		}
+
+		if r.Method == "POST" {
+			var body struct {
+				Transition struct {
+					ID string `json:"id"`
+				} `json:"transition"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Fatalf("Error decoding request: %v", err)
+			}
+			*executed = append(*executed, body.Transition.ID)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		*lists++
+
+		response := transitionsResponse{Transitions: []Transition{
+			{ID: "21", Name: "Start", To: Status{Name: "In Progress"}},
+			{ID: "31", Name: "Finish", To: Status{Name: "Done"}},
+		}}
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			t.Fatalf("Error encoding response: %v", err)
+		}
+	}))
+}
+
+func TestGetTransitions(t *testing.T) {
+	var executed []string
+	var lists int
+	ts := transitionsServer(t, &executed, &lists)
+	defer ts.Close()
+
+	transitions, err := newTestJiraClient(t, ts.URL).GetTransitions(context.Background(), "TU-1")
+	if err != nil {
+		t.Fatalf("Error getting transitions: %v", err)
+	}
+
+	if len(transitions) != 2 || transitions[1].ID != "31" || transitions[1].To.Name != "Done" {
+		t.Errorf("Expected two transitions ending in Done, got %+v", transitions)
+	}
+}
+
+func TestTransitionIssue(t *testing.T) {
+	var executed []string
+	var lists int
+	ts := transitionsServer(t, &executed, &lists)
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+
+	if err := client.TransitionToStatus(context.Background(), "TU-1", "done"); err != nil {
+		t.Fatalf("Error transitioning issue: %v", err)
+	}
+
+	if len(executed) != 1 || executed[0] != "31" {
+		t.Errorf("Expected transition %s to be executed, got %v", "31", executed)
+	}
+	if lists != 1 {
+		t.Errorf("Expected the transitions to be listed once, got %d lists", lists)
+	}
+}
+
+func TestTransitionIssueInvalid(t *testing.T) {
+	var executed []string
+	var lists int
+	ts := transitionsServer(t, &executed, &lists)
+	defer ts.Close()
+
+	client := newTestJiraClient(t, ts.URL)
+
+	err := client.TransitionIssue(context.Background(), "TU-1", "99")
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "transition 99 is not available for TU-1") || !strings.Contains(err.Error(), "Done") {
+		t.Errorf("Expected a descriptive error listing available transitions, got %q", err)
+	}
+
+	if len(executed) != 0 {
+		t.Errorf("Expected no transition to be executed, got %v", executed)
+	}
+}
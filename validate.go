@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// NotionDatabase is a database's schema as returned by GET /databases/{id}.
+type NotionDatabase struct {
+	ID         string                            `json:"id"`
+	Properties map[string]NotionDatabaseProperty `json:"properties"`
+}
+
+// NotionDatabaseProperty is one column of a database. Type is a Notion
+// property type such as NotionSelect.
+type NotionDatabaseProperty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// RetrieveDatabase returns the schema of databaseID.
+func (c *NotionClient) RetrieveDatabase(ctx context.Context, databaseID string) (NotionDatabase, error) {
+	var database NotionDatabase
+	if _, err := c.do(ctx, "GET", c.baseURL+"/databases/"+databaseID, nil, &database); err != nil {
+		return NotionDatabase{}, err
+	}
+	return database, nil
+}
+
+// CheckJiraKeyProperty fails unless databaseID has notion's JiraKeyProperty
+// as a rich_text or title property, without which no page could be found
+// again and every run would create duplicates.
+func CheckJiraKeyProperty(ctx context.Context, notion *NotionClient, databaseID string) error {
+	database, err := notion.RetrieveDatabase(ctx, databaseID)
+	if err != nil {
+		return err
+	}
+	if problem := jiraKeyProblem(database, notion.JiraKeyProperty); problem != "" {
+		return errors.New(problem)
+	}
+	return nil
+}
+
+// jiraKeyProblem describes what is wrong with database's keyProperty, or
+// returns "".
+func jiraKeyProblem(database NotionDatabase, keyProperty string) string {
+	property, ok := database.Properties[keyProperty]
+	switch {
+	case !ok:
+		return fmt.Sprintf("Notion property %q for the Jira key does not exist", keyProperty)
+	case property.Type != NotionRichText && property.Type != NotionTitle:
+		return fmt.Sprintf("Notion property %q for the Jira key is %s, not rich_text or title", keyProperty, property.Type)
+	}
+	return ""
+}
+
+// ValidateMapping checks mapping against the live schemas: that the sync
+// knows each Jira field and Jira has it, and that each Notion property exists
+// in databaseID with the mapped type. notion's JiraKeyProperty is checked as
+// by CheckJiraKeyProperty, and its JiraIDProperty and the properties of
+// metadata, when in use, as rich_text, a date and a url. It returns every
+// problem found, so a CI run can report them together; the error is only for
+// a failed schema fetch.
+func ValidateMapping(ctx context.Context, mapping []FieldMapping, jira *JiraClient, notion *NotionClient, databaseID string, metadata PageMetadata) ([]string, error) {
+	fields, err := jira.Fields(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f.ID] = true
+	}
+
+	database, err := notion.RetrieveDatabase(ctx, databaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	checkProperty := func(name, notionType string) {
+		property, ok := database.Properties[name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("Notion property %q does not exist", name))
+		case property.Type != notionType:
+			problems = append(problems, fmt.Sprintf("Notion property %q is %s, not %s", name, property.Type, notionType))
+		}
+	}
+
+	for _, m := range mapping {
+		_, single := jiraFieldValue(Issue{}, m.JiraField)
+		_, list := jiraFieldValues(Issue{}, m.JiraField)
+		_, number := jiraFieldNumber(Issue{}, m.JiraField, discardLogger)
+		switch {
+		case !single && !list && !number && !userListField(m.JiraField):
+			problems = append(problems, fmt.Sprintf("Jira field %q is not supported by the sync", m.JiraField))
+		case !known[jiraFieldID(m.JiraField)]:
+			problems = append(problems, fmt.Sprintf("Jira field %q does not exist", jiraFieldID(m.JiraField)))
+		}
+
+		if !supportedNotionType(m.NotionType) {
+			problems = append(problems, fmt.Sprintf("Notion type %q for property %q is not supported", m.NotionType, m.NotionProperty))
+			continue
+		}
+		checkProperty(m.NotionProperty, m.NotionType)
+		if m.FallbackProperty != "" {
+			checkProperty(m.FallbackProperty, NotionRichText)
+		}
+	}
+
+	if problem := jiraKeyProblem(database, notion.JiraKeyProperty); problem != "" {
+		problems = append(problems, problem)
+	}
+	if metadata.LastSyncedProperty != "" {
+		checkProperty(metadata.LastSyncedProperty, NotionDate)
+	}
+	if notion.JiraIDProperty != "" {
+		checkProperty(notion.JiraIDProperty, NotionRichText)
+	}
+	if name := metadata.jiraURLProperty(); name != "" {
+		checkProperty(name, NotionURL)
+	}
+
+	return problems, nil
+}
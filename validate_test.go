@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateMapping(t *testing.T) {
+	jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": "issuekey", "name": "Key"}, {"id": "summary", "name": "Summary"}, {"id": "status", "name": "Status"}]`))
+	}))
+	defer jiraServer.Close()
+
+	notionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/databases/db-1" {
+			t.Errorf("Expected GET /databases/db-1, got %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"id": "db-1", "properties": {
+			"Jira Key": {"id": "a", "name": "Jira Key", "type": "rich_text"},
+			"Name": {"id": "title", "name": "Name", "type": "title"},
+			"Status": {"id": "b", "name": "Status", "type": "status"},
+			"Last Synced": {"id": "c", "name": "Last Synced", "type": "date"}
+		}}`))
+	}))
+	defer notionServer.Close()
+
+	mapping := []FieldMapping{
+		{JiraField: "key", NotionProperty: defaultJiraKeyProperty, NotionType: NotionRichText},
+		{JiraField: "summary", NotionProperty: "Name", NotionType: NotionTitle},
+		{JiraField: "status", NotionProperty: "Status", NotionType: NotionSelect},
+		{JiraField: "priority", NotionProperty: "Priority", NotionType: NotionSelect},
+	}
+
+	metadata := PageMetadata{JiraWebBaseURL: "https://example.atlassian.net", LastSyncedProperty: "Last Synced"}
+	problems, err := ValidateMapping(context.Background(), mapping, newTestJiraClient(t, jiraServer.URL), newTestNotionClient(notionServer.URL), "db-1", metadata)
+	if err != nil {
+		t.Fatalf("Error validating mapping: %v", err)
+	}
+
+	expected := []string{
+		`Notion property "Status" is status, not select`,
+		`Jira field "priority" does not exist`,
+		`Notion property "Priority" does not exist`,
+		`Notion property "Jira URL" does not exist`,
+	}
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected problems %q, got %q", expected, problems)
+	}
+}
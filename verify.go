@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// verifyWrite compares the properties just written to pageID with what
+// Notion returns for it, when VerifyWrites is set, and logs a warning for
+// each that differs. A failed read is logged too; the write itself already
+// succeeded. Properties NotionPage.Values doesn't parse, such as relations,
+// aren't compared.
+func (c *NotionClient) verifyWrite(ctx context.Context, pageID string, sent map[string]interface{}) {
+	if !c.VerifyWrites || c.DryRun {
+		return
+	}
+
+	stored, err := c.GetPage(ctx, pageID)
+	if err != nil {
+		c.log().Warn("reading page back to verify write failed", "pageID", pageID, "error", err)
+		return
+	}
+
+	for name, property := range sent {
+		value, ok := writtenValue(property)
+		if !ok {
+			continue
+		}
+		if actual, found := stored[name]; !found || !sameValue(value, actual) {
+			c.log().Warn("Notion stored a property differently from what was written", "pageID", pageID, "property", name, "written", value, "stored", actual)
+		}
+	}
+}
+
+// writtenValue parses a property as sent to Notion into the form
+// NotionPage.Values reads it back in. It is false for types Values leaves
+// out.
+func writtenValue(property interface{}) (interface{}, bool) {
+	data, err := json.Marshal(property)
+	if err != nil {
+		return nil, false
+	}
+	var value struct {
+		Title    []NotionRichTextObject `json:"title"`
+		RichText []NotionRichTextObject `json:"rich_text"`
+		Select   *struct {
+			Name string `json:"name"`
+		} `json:"select"`
+		MultiSelect []struct {
+			Name string `json:"name"`
+		} `json:"multi_select"`
+		Date *struct {
+			Start string `json:"start"`
+		} `json:"date"`
+		Number *float64 `json:"number"`
+		URL    *string  `json:"url"`
+	}
+	var types map[string]json.RawMessage
+	if json.Unmarshal(data, &value) != nil || json.Unmarshal(data, &types) != nil || len(types) != 1 {
+		return nil, false
+	}
+
+	for notionType := range types {
+		switch notionType {
+		case NotionTitle, NotionRichText:
+			var b strings.Builder
+			for _, part := range append(value.Title, value.RichText...) {
+				b.WriteString(part.Text.Content)
+			}
+			return b.String(), true
+		case NotionSelect:
+			if value.Select == nil || value.Select.Name == "" {
+				return nil, true
+			}
+			return value.Select.Name, true
+		case NotionMultiSelect:
+			options := []string{}
+			for _, option := range value.MultiSelect {
+				options = append(options, option.Name)
+			}
+			return options, true
+		case NotionDate:
+			if value.Date == nil {
+				return nil, true
+			}
+			if t, err := parseNotionDate(value.Date.Start); err == nil {
+				return t, true
+			}
+			return nil, false
+		case NotionNumber:
+			if value.Number == nil {
+				return nil, true
+			}
+			return *value.Number, true
+		case NotionURL:
+			if value.URL == nil {
+				return "", true
+			}
+			return *value.URL, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestVerifyWritesReportsMismatch(t *testing.T) {
+	server := newNotionTestServer(t)
+	server.Get = &notionResponse{Body: `{"id": "page-1", "properties": {
+		"Name": {"type": "title", "title": [{"plain_text": "Launch the onboarding bet"}]},
+		"Status": {"type": "select", "select": {"name": "To Do"}}
+	}}`}
+
+	var logs bytes.Buffer
+	notion := server.NotionClient()
+	notion.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	notion.VerifyWrites = true
+
+	properties := map[string]interface{}{
+		"Name":   map[string]interface{}{"title": richText("Launch the onboarding bet")},
+		"Status": map[string]interface{}{"select": map[string]interface{}{"name": "In Progress"}},
+	}
+	if _, err := notion.CreatePage(context.Background(), "db-1", properties); err != nil {
+		t.Fatalf("Error creating page: %v", err)
+	}
+
+	if _, ok := server.LastRequest("GET", "/pages/page-1"); !ok {
+		t.Fatalf("Expected the page to be read back, got %+v", server.Requests())
+	}
+	output := logs.String()
+	if !strings.Contains(output, "property=Status") || !strings.Contains(output, `written="In Progress"`) || !strings.Contains(output, `stored="To Do"`) {
+		t.Errorf("Expected a warning about Status, got %q", output)
+	}
+	if strings.Contains(output, "property=Name") {
+		t.Errorf("Expected the matching Name not to be reported, got %q", output)
+	}
+}
+
+func TestVerifyWritesOffByDefault(t *testing.T) {
+	server := newNotionTestServer(t)
+
+	if err := server.NotionClient().UpdatePage(context.Background(), "page-1", map[string]interface{}{}); err != nil {
+		t.Fatalf("Error updating page: %v", err)
+	}
+
+	if _, ok := server.LastRequest("GET", "/pages/page-1"); ok {
+		t.Errorf("Expected no read back without VerifyWrites")
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// NotionUserIDs maps Jira account ids to Notion user ids, for writing the
+// "watchers" and "voters" fields to a NotionPeople property. Jira and Notion
+// share no user ids, so it has to be filled in, e.g. from the config file's
+// notionUsers.
+var NotionUserIDs = map[string]string{}
+
+// userListField reports whether field is a mapping field whose users are
+// fetched separately from the issue: "watchers" or "voters".
+func userListField(field string) bool {
+	return field == "watchers" || field == "voters"
+}
+
+// FetchWatchers returns the users watching issueKey.
+func (c *JiraClient) FetchWatchers(ctx context.Context, issueKey string) ([]User, error) {
+	var page struct {
+		Watchers []User `json:"watchers"`
+	}
+	if err := c.getIssueResource(ctx, issueKey, "/watchers", &page); err != nil {
+		return nil, err
+	}
+	return page.Watchers, nil
+}
+
+// FetchVoters returns the users who voted for issueKey. Jira only lists them
+// to users allowed to view voters.
+func (c *JiraClient) FetchVoters(ctx context.Context, issueKey string) ([]User, error) {
+	var page struct {
+		Voters []User `json:"voters"`
+	}
+	if err := c.getIssueResource(ctx, issueKey, "/votes", &page); err != nil {
+		return nil, err
+	}
+	return page.Voters, nil
+}
+
+func (c *JiraClient) getIssueResource(ctx context.Context, issueKey, path string, out interface{}) error {
+	issueURL, err := c.issueURL(issueKey)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, "GET", issueURL+path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return httpError(resp.StatusCode, readErrorBody(resp))
+	}
+	return c.decode(resp, out)
+}
+
+// userListProperties fetches the users behind each "watchers" or "voters"
+// mapping and builds its property with usersProperty. Unsupported types and
+// users left out of a people property are logged to jira's logger.
+func userListProperties(ctx context.Context, jira *JiraClient, issue Issue, mapping []FieldMapping) (map[string]interface{}, error) {
+	properties := map[string]interface{}{}
+
+	for _, m := range mapping {
+		if !userListField(m.JiraField) {
+			continue
+		}
+
+		fetch := jira.FetchWatchers
+		if m.JiraField == "voters" {
+			fetch = jira.FetchVoters
+		}
+		users, err := fetch(ctx, issue.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		property, ok := usersProperty(users, m.NotionType)
+		if !ok {
+			jira.log().Warn("skipping unsupported Notion type", "type", m.NotionType, "property", m.NotionProperty, "issueKey", issue.Key)
+			continue
+		}
+		properties[m.NotionProperty] = property
+
+		if m.NotionType != NotionPeople {
+			continue
+		}
+		unmapped := unmappedUsers(users)
+		for _, user := range unmapped {
+			jira.log().Warn("no Notion user for Jira user", "field", m.JiraField, "issueKey", issue.Key, "accountID", user.AccountID, "displayName", user.DisplayName)
+		}
+		if m.FallbackProperty != "" {
+			properties[m.FallbackProperty], _ = usersProperty(unmapped, NotionRichText)
+		}
+	}
+
+	return properties, nil
+}
+
+// usersProperty writes users to a NotionPeople property, as the Notion users
+// NotionUserIDs maps them to, or to a rich_text property as a list of display
+// names. A people property can't hold names, so users missing from
+// NotionUserIDs are left out of it; the mapping's
+// FallbackProperty lists them by name instead, or map the field to rich_text
+// to list everyone.
+func usersProperty(users []User, notionType string) (map[string]interface{}, bool) {
+	switch notionType {
+	case NotionPeople:
+		people := []interface{}{}
+		for _, user := range users {
+			id, ok := NotionUserIDs[user.AccountID]
+			if !ok {
+				continue
+			}
+			people = append(people, map[string]interface{}{"object": "user", "id": id})
+		}
+		return map[string]interface{}{"people": people}, true
+	case NotionRichText:
+		names := make([]string, 0, len(users))
+		for _, user := range users {
+			names = append(names, user.DisplayName)
+		}
+		return map[string]interface{}{"rich_text": richText(strings.Join(names, ", "))}, true
+	}
+	return nil, false
+}
+
+// unmappedUsers returns the users NotionUserIDs has no Notion user for.
+func unmappedUsers(users []User) []User {
+	var unmapped []User
+	for _, user := range users {
+		if _, ok := NotionUserIDs[user.AccountID]; !ok {
+			unmapped = append(unmapped, user)
+		}
+	}
+	return unmapped
+}
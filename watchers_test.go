@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchWatchersFallsBackToNames(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/issue/TU-1/watchers" {
+			t.Errorf("Expected the watchers of TU-1 to be requested, got %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"watchCount": 2, "isWatching": false, "watchers": [
+			{"accountId": "5b10a2844c20165700ede21g", "displayName": "Ada Lovelace"},
+			{"accountId": "5b10ac8d82e05b22cc7d4ef5", "displayName": "Grace Hopper"}
+		]}`))
+	}))
+	defer ts.Close()
+
+	jira := newTestJiraClient(t, ts.URL)
+	watchers, err := jira.FetchWatchers(context.Background(), "TU-1")
+	if err != nil {
+		t.Fatalf("Error fetching watchers: %v", err)
+	}
+
+	if len(watchers) != 2 || watchers[0].AccountID != "5b10a2844c20165700ede21g" || watchers[1].DisplayName != "Grace Hopper" {
+		t.Fatalf("Expected Ada Lovelace and Grace Hopper, got %+v", watchers)
+	}
+
+	original := NotionUserIDs
+	defer func() { NotionUserIDs = original }()
+	NotionUserIDs = map[string]string{"5b10a2844c20165700ede21g": "notion-user-1"}
+
+	var buf bytes.Buffer
+	jira.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+	mapping := []FieldMapping{{JiraField: "watchers", NotionProperty: "Watchers", NotionType: NotionPeople, FallbackProperty: "Other Watchers"}}
+	properties, err := userListProperties(context.Background(), jira, Issue{Key: "TU-1"}, mapping)
+	if err != nil {
+		t.Fatalf("Error building properties: %v", err)
+	}
+
+	assertJSON(t, properties, `{
+		"Watchers": {"people": [{"object": "user", "id": "notion-user-1"}]},
+		"Other Watchers": {"rich_text": [{"type": "text", "text": {"content": "Grace Hopper"}}]}
+	}`)
+
+	if logged := buf.String(); !strings.Contains(logged, "level=WARN") || !strings.Contains(logged, "accountID=5b10ac8d82e05b22cc7d4ef5") || !strings.Contains(logged, "issueKey=TU-1") {
+		t.Errorf("Expected a warning for Grace Hopper, got %s", logged)
+	}
+}
+
+func TestUsersPropertyPeople(t *testing.T) {
+	original := NotionUserIDs
+	defer func() { NotionUserIDs = original }()
+	NotionUserIDs = map[string]string{"5b10a2844c20165700ede21g": "notion-user-1"}
+
+	property, ok := usersProperty([]User{
+		{AccountID: "5b10a2844c20165700ede21g", DisplayName: "Ada Lovelace"},
+		{AccountID: "5b10ac8d82e05b22cc7d4ef5", DisplayName: "Grace Hopper"},
+	}, NotionPeople)
+	if !ok {
+		t.Fatalf("Expected people to be supported")
+	}
+
+	assertJSON(t, property, `{"people": [{"object": "user", "id": "notion-user-1"}]}`)
+}
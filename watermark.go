@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// clockSkewBuffer is subtracted from the stored watermark so issues updated
+// just before the last run, but indexed after it, aren't missed.
+const clockSkewBuffer = 2 * time.Minute
+
+// jqlTimeLayout is the minute-precision format JQL accepts for dates. Jira
+// evaluates it in the searching user's time zone, so incrementalJQL formats
+// the watermark in a caller-supplied location.
+const jqlTimeLayout = "2006-01-02 15:04"
+
+// orderByPattern matches the ORDER BY keywords at the start of a string.
+var orderByPattern = regexp.MustCompile(`(?i)^ORDER\s+BY\s+`)
+
+// orderByIndex returns where jql's ORDER BY clause starts, including the
+// whitespace before it, and where its field list starts. The clause may be
+// all there is to jql. ORDER BY inside a quoted string doesn't count, and ok
+// is false when there is no clause.
+func orderByIndex(jql string) (start, fields int, ok bool) {
+	var quote, prev rune
+	escaped := false
+	for i, r := range jql {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if r == '\\' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case i == 0 || unicode.IsSpace(prev):
+			if m := orderByPattern.FindStringIndex(jql[i:]); m != nil {
+				return len(strings.TrimRightFunc(jql[:i], unicode.IsSpace)), i + m[1], true
+			}
+		}
+		prev = r
+	}
+	return 0, 0, false
+}
+
+type watermarkFile struct {
+	LastSynced time.Time `json:"lastSynced"`
+}
+
+// loadWatermark returns the high-watermark stored at path. A missing file
+// means nothing has been synced yet and yields the zero time.
+func loadWatermark(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var w watermarkFile
+	if err := json.Unmarshal(data, &w); err != nil {
+		return time.Time{}, fmt.Errorf("reading watermark %s: %w", path, err)
+	}
+
+	return w.LastSynced, nil
+}
+
+func saveWatermark(path string, lastSynced time.Time) error {
+	data, err := json.Marshal(watermarkFile{LastSynced: lastSynced})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// incrementalJQL restricts jql to issues updated since the watermark, less
+// clockSkewBuffer. The clause is inserted ahead of any ORDER BY, and replaces
+// an empty filter when jql only orders. A zero watermark leaves jql
+// unchanged.
+func incrementalJQL(jql string, since time.Time, loc *time.Location) string {
+	if jql == "" {
+		jql = defaultJQL
+	}
+	if since.IsZero() {
+		return jql
+	}
+
+	clause := fmt.Sprintf(`updated >= "%s"`, since.Add(-clockSkewBuffer).In(loc).Format(jqlTimeLayout))
+
+	filter, orderBy := jql, ""
+	if start, _, ok := orderByIndex(jql); ok {
+		filter, orderBy = jql[:start], " "+strings.TrimSpace(jql[start:])
+	}
+	if strings.TrimSpace(filter) == "" {
+		return clause + orderBy
+	}
+
+	return fmt.Sprintf("(%s) AND %s%s", filter, clause, orderBy)
+}
+
+// highWatermark returns the latest updated time among issues, or previous if
+// none of them is newer.
+func highWatermark(issues []Issue, previous time.Time) time.Time {
+	latest := previous
+	for _, issue := range issues {
+		updated, err := parseJiraTime(issue.Fields.Updated)
+		if err != nil {
+			continue
+		}
+		if updated.After(latest) {
+			latest = updated
+		}
+	}
+	return latest
+}
+
+// parseSince turns a --since value into the time to sync from. It accepts a
+// Go duration such as "24h" or "90m", a number of days such as "7d", both
+// counted back from now, or an absolute RFC 3339 timestamp.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	var d time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid --since %q: expected a duration like 24h or 7d, or an RFC 3339 time", value)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		d, err = time.ParseDuration(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid --since %q: expected a duration like 24h or 7d, or an RFC 3339 time", value)
+		}
+	}
+
+	if d <= 0 {
+		return time.Time{}, fmt.Errorf("invalid --since %q: duration must be positive", value)
+	}
+	return now.Add(-d), nil
+}
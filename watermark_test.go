@@ -0,0 +1,116 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIncrementalJQL(t *testing.T) {
+	since := time.Date(2024, 3, 1, 12, 34, 0, 0, time.UTC)
+
+	got := incrementalJQL("project = TU", since, time.UTC)
+	expected := `(project = TU) AND updated >= "2024-03-01 12:32"`
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	got = incrementalJQL("project = TU ORDER BY updated ASC", since, time.UTC)
+	expected = `(project = TU) AND updated >= "2024-03-01 12:32" ORDER BY updated ASC`
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	got = incrementalJQL("ORDER BY priority DESC", since, time.UTC)
+	expected = `updated >= "2024-03-01 12:32" ORDER BY priority DESC`
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	got = incrementalJQL(`summary ~ "sort order by date" ORDER BY rank`, since, time.UTC)
+	expected = `(summary ~ "sort order by date") AND updated >= "2024-03-01 12:32" ORDER BY rank`
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	got = incrementalJQL(`summary ~ 'it\'s order by hand'`, since, time.UTC)
+	expected = `(summary ~ 'it\'s order by hand') AND updated >= "2024-03-01 12:32"`
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if got := incrementalJQL("project = TU", time.Time{}, time.UTC); got != "project = TU" {
+		t.Errorf("Expected a zero watermark to leave the JQL unchanged, got %q", got)
+	}
+}
+
+func TestWatermarkAdvances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watermark.json")
+
+	previous, err := loadWatermark(path)
+	if err != nil {
+		t.Fatalf("Error loading missing watermark: %v", err)
+	}
+	if !previous.IsZero() {
+		t.Errorf("Expected a zero watermark before the first sync, got %v", previous)
+	}
+
+	issues := []Issue{
+		{Key: "TU-1", Fields: IssueFields{Updated: "2024-03-01T12:00:00.000+0000"}},
+		{Key: "TU-2", Fields: IssueFields{Updated: "2024-03-02T08:30:00.000+0000"}},
+		{Key: "TU-3", Fields: IssueFields{Updated: ""}},
+	}
+
+	latest := highWatermark(issues, previous)
+	expected := time.Date(2024, 3, 2, 8, 30, 0, 0, time.UTC)
+	if !latest.Equal(expected) {
+		t.Errorf("Expected watermark %v, got %v", expected, latest)
+	}
+
+	if err := saveWatermark(path, latest); err != nil {
+		t.Fatalf("Error saving watermark: %v", err)
+	}
+
+	loaded, err := loadWatermark(path)
+	if err != nil {
+		t.Fatalf("Error loading watermark: %v", err)
+	}
+	if !loaded.Equal(expected) {
+		t.Errorf("Expected stored watermark %v, got %v", expected, loaded)
+	}
+
+	if older := highWatermark(issues[:1], loaded); !older.Equal(loaded) {
+		t.Errorf("Expected watermark not to move backwards, got %v", older)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"24h", now.Add(-24 * time.Hour)},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"2024-05-01T09:30:00Z", time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSince(tt.value, now)
+		if err != nil {
+			t.Errorf("Error parsing %q: %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.expected) {
+			t.Errorf("Expected parseSince(%q) to be %v, got %v", tt.value, tt.expected, got)
+		}
+	}
+
+	for _, value := range []string{"yesterday", "7days", "-24h", "2024-05-01"} {
+		if _, err := parseSince(value, now); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// maxWebhookBodySize bounds how much of a webhook request is read.
+const maxWebhookBodySize = 1 << 20
+
+// WebhookEvent is the part of a Jira webhook payload the sync reads.
+type WebhookEvent struct {
+	WebhookEvent string `json:"webhookEvent"`
+	Issue        struct {
+		Key string `json:"key"`
+	} `json:"issue"`
+}
+
+// WebhookHandler syncs a single issue whenever Jira reports it was created or
+// updated. Requests must prove they know Secret, either with an
+// X-Hub-Signature HMAC as Jira Cloud sends, or a secret query parameter for
+// Jira Server, which can't sign webhooks.
+type WebhookHandler struct {
+	Secret string
+	// Sync pushes one issue to Notion, for example via SyncIssue.
+	Sync func(ctx context.Context, issueKey string) error
+	// Logger receives a record of every webhook. A nil Logger discards
+	// them.
+	Logger *slog.Logger
+}
+
+func (h *WebhookHandler) log() *slog.Logger {
+	if h.Logger == nil {
+		return discardLogger
+	}
+	return h.Logger
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize))
+	if err != nil {
+		http.Error(w, "reading body", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorized(r, body) {
+		h.log().Warn("rejected webhook with a bad or missing secret", "remoteAddr", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.Issue.Key == "" {
+		http.Error(w, "expected a Jira issue event", http.StatusBadRequest)
+		return
+	}
+
+	switch event.WebhookEvent {
+	case "jira:issue_created", "jira:issue_updated":
+	default:
+		h.log().Info("ignoring webhook event", "event", event.WebhookEvent, "issueKey", event.Issue.Key)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := h.Sync(r.Context(), event.Issue.Key); err != nil {
+		h.log().Error("webhook sync failed", "event", event.WebhookEvent, "issueKey", event.Issue.Key, "error", err)
+		http.Error(w, "sync failed", http.StatusBadGateway)
+		return
+	}
+
+	h.log().Info("synced issue from webhook", "event", event.WebhookEvent, "issueKey", event.Issue.Key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorized checks the request's signature or secret against h.Secret in
+// constant time. An empty Secret rejects everything.
+func (h *WebhookHandler) authorized(r *http.Request, body []byte) bool {
+	if h.Secret == "" {
+		return false
+	}
+
+	if signature, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature"), "sha256="); ok {
+		mac := hmac.New(sha256.New, []byte(h.Secret))
+		mac.Write(body)
+		expected := hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(signature), []byte(expected))
+	}
+
+	secret := r.URL.Query().Get("secret")
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(h.Secret)) == 1
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const sampleWebhook = `{
+	"timestamp": 1714560000000,
+	"webhookEvent": "jira:issue_updated",
+	"issue_event_type_name": "issue_generic",
+	"issue": {"id": "10001", "key": "TU-7", "fields": {"summary": "Bet"}}
+}`
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookHandlerSyncsIssue(t *testing.T) {
+	var synced []string
+	handler := &WebhookHandler{Secret: "s3cret", Sync: func(ctx context.Context, issueKey string) error {
+		synced = append(synced, issueKey)
+		return nil
+	}}
+
+	req := httptest.NewRequest("POST", "/webhook", strings.NewReader(sampleWebhook))
+	req.Header.Set("X-Hub-Signature", sign("s3cret", sampleWebhook))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, rec.Code)
+	}
+
+	if len(synced) != 1 || synced[0] != "TU-7" {
+		t.Errorf("Expected TU-7 to be synced, got %v", synced)
+	}
+}
+
+func TestWebhookHandlerQuerySecret(t *testing.T) {
+	var synced []string
+	handler := &WebhookHandler{Secret: "s3cret", Sync: func(ctx context.Context, issueKey string) error {
+		synced = append(synced, issueKey)
+		return nil
+	}}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook?secret=s3cret", strings.NewReader(sampleWebhook)))
+
+	if rec.Code != http.StatusNoContent || len(synced) != 1 {
+		t.Errorf("Expected the issue to be synced, got status %d and %v", rec.Code, synced)
+	}
+}
+
+func TestWebhookHandlerRejectsBadSecret(t *testing.T) {
+	handler := &WebhookHandler{Secret: "s3cret", Sync: func(ctx context.Context, issueKey string) error {
+		t.Errorf("Expected no sync, got %s", issueKey)
+		return nil
+	}}
+
+	requests := map[string]*http.Request{
+		"missing":     httptest.NewRequest("POST", "/webhook", strings.NewReader(sampleWebhook)),
+		"wrong query": httptest.NewRequest("POST", "/webhook?secret=guess", strings.NewReader(sampleWebhook)),
+		"wrong signature": func() *http.Request {
+			req := httptest.NewRequest("POST", "/webhook", strings.NewReader(sampleWebhook))
+			req.Header.Set("X-Hub-Signature", sign("guess", sampleWebhook))
+			return req
+		}(),
+	}
+
+	for name, req := range requests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected %s secret to be rejected with %d, got %d", name, http.StatusUnauthorized, rec.Code)
+		}
+	}
+}